@@ -3,6 +3,29 @@ package main
 import (
 	"fmt"
 	"net/http"
+
+	"github.com/ildx/greenlight/internal/i18n"
+	"github.com/ildx/greenlight/internal/validator"
+)
+
+// stable, machine-readable codes sent with every error response, next to the
+// human-readable message. validation failures carry an additional code per
+// field, from the validator package
+const (
+	errCodeBadRequest                 = "bad_request"
+	errCodeFailedValidation           = "failed_validation"
+	errCodeNotFound                   = "not_found"
+	errCodeMethodNotAllowed           = "method_not_allowed"
+	errCodeNotAcceptable              = "not_acceptable"
+	errCodeEditConflict               = "edit_conflict"
+	errCodePreconditionFailed         = "precondition_failed"
+	errCodeRateLimitExceeded          = "rate_limit_exceeded"
+	errCodeInvalidCredentials         = "invalid_credentials"
+	errCodeInvalidAuthenticationToken = "invalid_authentication_token"
+	errCodeAuthenticationRequired     = "authentication_required"
+	errCodeInactiveAccount            = "inactive_account"
+	errCodeNotPermitted               = "not_permitted"
+	errCodeServerError                = "server_error"
 )
 
 // log errors along with the request method and URL
@@ -15,23 +38,95 @@ func (app *application) logError(r *http.Request, err error) {
 	app.logger.Error(err.Error(), "method", method, "uri", uri)
 }
 
-// send JSON-formatted errors to the client with a given status code
-func (app *application) errorResponse(w http.ResponseWriter, r *http.Request, status int, message any) {
-	env := envelope{"error": message}
+// send errors to the client with a given status code and error code, in the negotiated
+// format and language. message is either a string or, for validation failures, the
+// validator; args are the arguments for translations of string messages
+func (app *application) errorResponse(w http.ResponseWriter, r *http.Request, status int, code string, message any, args ...any) {
+	lang := i18n.Negotiate(r.Header.Get("Accept-Language"))
+
+	var (
+		detail  string                  // the message of the error
+		fields  map[string]string       // the first error of each field of a validation failure
+		codes   map[string]string       // and their codes
+		details []validator.FieldErrors // every error, grouped by field
+	)
+
+	switch message := message.(type) {
+	case string:
+		detail = i18n.Message(lang, code, message, args...)
+	case *validator.Validator:
+		detail = i18n.Message(lang, code, "the request contains invalid fields")
+		fields = i18n.Errors(lang, message)
+		codes = message.Codes
+		details = i18n.Grouped(lang, message)
+	}
+
+	env := envelope{"code": code, "error": detail}
+	if fields != nil {
+		env = envelope{"code": code, "error": fields, "field_codes": codes, "details": details}
+	}
+
+	problem := app.wantsProblemDetails(r)
+	if problem {
+		env = problemDetails(r, status, code, detail, fields, codes, details)
+	}
+
+	w.Header().Set("Content-Language", lang)
+	w.Header().Add("Vary", "Accept-Language")
 
 	// if this returns an error, log it, and fall back
 	// to sending empty response with 500 Internal Server Error
-	err := app.writeJSON(w, status, env, nil)
+	err := app.renderError(w, r, status, env, problem)
 	if err != nil {
 		app.logError(r, err)
 		w.WriteHeader(500)
 	}
 }
 
+// wantsProblemDetails reports whether errors should be RFC 9457 problem
+// details, which clients opt into with Accept: application/problem+json
+// unless the server is configured to always send them
+func (app *application) wantsProblemDetails(r *http.Request) bool {
+	if app.config.errors.problemDetails {
+		return true
+	}
+
+	for _, accepted := range acceptedMediaTypes(r) {
+		if accepted.mediaType == "application/problem+json" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// problemDetails builds an RFC 9457 problem details object. the problem types
+// carry no semantics beyond the status code, so they are all about:blank; the
+// error code goes in the "code" extension, and validation failures list their
+// field errors and codes in the "errors", "field_codes" and "details" extensions
+func problemDetails(r *http.Request, status int, code, detail string, fields, codes map[string]string, details []validator.FieldErrors) envelope {
+	problem := envelope{
+		"type":     "about:blank",
+		"title":    http.StatusText(status),
+		"status":   status,
+		"detail":   detail,
+		"instance": r.URL.Path,
+		"code":     code,
+	}
+
+	if fields != nil {
+		problem["errors"] = fields
+		problem["field_codes"] = codes
+		problem["details"] = details
+	}
+
+	return problem
+}
+
 // handle edit conflicts
 func (app *application) editConflictResponse(w http.ResponseWriter, r *http.Request) {
 	message := "unable to update the record due to an edit conflict, please try again"
-	app.errorResponse(w, r, http.StatusConflict, message)
+	app.errorResponse(w, r, http.StatusConflict, errCodeEditConflict, message)
 }
 
 // handle unexpected server problems
@@ -39,41 +134,53 @@ func (app *application) serverErrorResponse(w http.ResponseWriter, r *http.Reque
 	app.logError(r, err)
 
 	message := "the server encountered a problem and could not process your request"
-	app.errorResponse(w, r, http.StatusInternalServerError, message)
+	app.errorResponse(w, r, http.StatusInternalServerError, errCodeServerError, message)
+}
+
+// handle stale If-Match preconditions
+func (app *application) preconditionFailedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the resource has been modified since it was retrieved, fetch it again and retry"
+	app.errorResponse(w, r, http.StatusPreconditionFailed, errCodePreconditionFailed, message)
 }
 
 // handle 404
 func (app *application) notFoundResponse(w http.ResponseWriter, r *http.Request) {
 	message := "the requested resource could not be found"
-	app.errorResponse(w, r, http.StatusNotFound, message)
+	app.errorResponse(w, r, http.StatusNotFound, errCodeNotFound, message)
 }
 
 // handle 405
 func (app *application) methodNotAllowedResponse(w http.ResponseWriter, r *http.Request) {
 	message := fmt.Sprintf("the %s method is not supported for this resource", r.Method)
-	app.errorResponse(w, r, http.StatusMethodNotAllowed, message)
+	app.errorResponse(w, r, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, message, r.Method)
+}
+
+// handle Accept headers that rule out every format we can render
+func (app *application) notAcceptableResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the resource can only be represented as application/json, application/xml, application/vnd.msgpack or, for lists, text/csv"
+	app.errorResponse(w, r, http.StatusNotAcceptable, errCodeNotAcceptable, message)
 }
 
 // handle bad requests
 func (app *application) badRequestResponse(w http.ResponseWriter, r *http.Request, err error) {
-	app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+	app.errorResponse(w, r, http.StatusBadRequest, errCodeBadRequest, err.Error())
 }
 
 // handle validation failures
-func (app *application) failedValidationResponse(w http.ResponseWriter, r *http.Request, errors map[string]string) {
-	app.errorResponse(w, r, http.StatusUnprocessableEntity, errors)
+func (app *application) failedValidationResponse(w http.ResponseWriter, r *http.Request, v *validator.Validator) {
+	app.errorResponse(w, r, http.StatusUnprocessableEntity, errCodeFailedValidation, v)
 }
 
 // handle rate limit exceeded
 func (app *application) rateLimitExceededResponse(w http.ResponseWriter, r *http.Request) {
 	message := "rate limit exceeded"
-	app.errorResponse(w, r, http.StatusTooManyRequests, message)
+	app.errorResponse(w, r, http.StatusTooManyRequests, errCodeRateLimitExceeded, message)
 }
 
 // handle invalid credentials
 func (app *application) invalidCredentialsResponse(w http.ResponseWriter, r *http.Request) {
 	message := "invalid authentication credentials"
-	app.errorResponse(w, r, http.StatusUnauthorized, message)
+	app.errorResponse(w, r, http.StatusUnauthorized, errCodeInvalidCredentials, message)
 }
 
 // handle invalid authentication tokens
@@ -81,20 +188,20 @@ func (app *application) invalidAuthenticationTokenResponse(w http.ResponseWriter
 	w.Header().Set("WWW-Authenticate", "Bearer")
 
 	message := "invalid or missing authentication token"
-	app.errorResponse(w, r, http.StatusUnauthorized, message)
+	app.errorResponse(w, r, http.StatusUnauthorized, errCodeInvalidAuthenticationToken, message)
 }
 
 func (app *application) authenticationRequiredResponse(w http.ResponseWriter, r *http.Request) {
 	message := "you must be authenticated to access this resource"
-	app.errorResponse(w, r, http.StatusUnauthorized, message)
+	app.errorResponse(w, r, http.StatusUnauthorized, errCodeAuthenticationRequired, message)
 }
 
 func (app *application) inactiveAccountResponse(w http.ResponseWriter, r *http.Request) {
 	message := "your user account must be activated to access this resource"
-	app.errorResponse(w, r, http.StatusForbidden, message)
+	app.errorResponse(w, r, http.StatusForbidden, errCodeInactiveAccount, message)
 }
 
 func (app *application) notPermittedResponse(w http.ResponseWriter, r *http.Request) {
 	message := "your user account doesn't have the necessary permissions to access this resource"
-	app.errorResponse(w, r, http.StatusForbidden, message)
+	app.errorResponse(w, r, http.StatusForbidden, errCodeNotPermitted, message)
 }
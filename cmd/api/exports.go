@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ildx/greenlight/internal/data"
+	"github.com/ildx/greenlight/internal/validator"
+)
+
+// export formats, which match the ones accepted by the import
+const (
+	exportFormatNDJSON = "ndjson"
+	exportFormatCSV    = "csv"
+)
+
+// get /v1/movies/export
+func (app *application) exportMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	qs := r.URL.Query()
+
+	filter := app.readMovieFilter(qs, v)
+	format := app.readString(qs, "format", exportFormatNDJSON)
+
+	v.Check(validator.PermittedValue(format, exportFormatNDJSON, exportFormatCSV), "format", validator.CodeNotPermitted, "must be ndjson or csv")
+
+	err := app.canonicalizeMovieFilter(&filter)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if data.ValidateMovieFilter(v, filter); !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	// exports outlive the server's WriteTimeout, so they get their own deadline
+	deadline := time.Now().Add(app.config.export.writeTimeout)
+
+	rc := http.NewResponseController(w)
+
+	err = rc.SetWriteDeadline(deadline)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	ctx, cancel := context.WithDeadline(r.Context(), deadline)
+	defer cancel()
+
+	filename := fmt.Sprintf("movies-%s.%s", time.Now().UTC().Format("20060102"), format)
+
+	contentType := "application/x-ndjson"
+	if format == exportFormatCSV {
+		contentType = "text/csv; charset=utf-8"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+
+	buf := bufio.NewWriter(w)
+
+	var write func(movie *data.Movie) error
+	flush := buf.Flush
+
+	switch format {
+	case exportFormatCSV:
+		cw := csv.NewWriter(buf)
+
+		write = func(movie *data.Movie) error {
+			return cw.Write([]string{
+				strconv.FormatInt(movie.ID, 10),
+				movie.Title,
+				strconv.Itoa(int(movie.Year)),
+				strconv.Itoa(int(movie.Runtime)),
+				strings.Join(movie.Genres, "|"),
+				strconv.Itoa(int(movie.Version)),
+			})
+		}
+
+		flush = func() error {
+			cw.Flush()
+			if err := cw.Error(); err != nil {
+				return err
+			}
+			return buf.Flush()
+		}
+
+		// same columns as the import, plus the id and version; write errors
+		// stick to the csv.Writer, so they surface when flushing
+		_ = cw.Write([]string{"id", "title", "year", "runtime", "genres", "version"})
+
+	default:
+		enc := json.NewEncoder(buf)
+
+		write = func(movie *data.Movie) error {
+			return enc.Encode(movie)
+		}
+	}
+
+	// flush each batch to the client, so that nothing piles up in memory
+	err = app.models.Movies.Export(ctx, filter, func(movies []*data.Movie) error {
+		for _, movie := range movies {
+			err := write(movie)
+			if err != nil {
+				return err
+			}
+		}
+
+		err := flush()
+		if err != nil {
+			return err
+		}
+
+		return rc.Flush()
+	})
+
+	// an empty export still sends the CSV header
+	if err == nil {
+		err = flush()
+	}
+
+	// the status line has been sent, so all that's left is to log the error
+	// and cut the response short, which tells the client the dump is incomplete
+	if err != nil {
+		app.logError(r, err)
+		panic(http.ErrAbortHandler)
+	}
+}
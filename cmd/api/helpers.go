@@ -1,6 +1,9 @@
 package main
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,7 +12,9 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/ildx/greenlight/internal/data"
 	"github.com/ildx/greenlight/internal/validator"
 
 	"github.com/julienschmidt/httprouter"
@@ -54,35 +59,173 @@ func (app *application) readInt(qs url.Values, key string, defaultValue int, v *
 	}
 	i, err := strconv.Atoi(s)
 	if err != nil {
-		v.AddError(key, "must be an integer value")
+		v.AddError(key, validator.CodeInvalid, "must be an integer value")
 		return defaultValue
 	}
 	return i
 }
 
+// return query string value as bool
+func (app *application) readBool(qs url.Values, key string, defaultValue bool, v *validator.Validator) bool {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		v.AddError(key, validator.CodeInvalid, "must be a boolean value")
+		return defaultValue
+	}
+	return b
+}
+
 // envolope type for wrapping responses
 type envelope map[string]any
 
-// write json to responses
-func (app *application) writeJSON(w http.ResponseWriter, status int, data envelope, headers http.Header) error {
-	j, err := json.MarshalIndent(data, "", "\t")
+// project keeps only the given fields of the JSON representation of a
+// value, or of every element if it's a slice; no fields means all of them
+func (app *application) project(value any, fields []string) (any, error) {
+	if len(fields) == 0 {
+		return value, nil
+	}
+
+	j, err := json.Marshal(value)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	pick := func(object map[string]json.RawMessage) map[string]json.RawMessage {
+		picked := make(map[string]json.RawMessage, len(fields))
+		for _, field := range fields {
+			if v, ok := object[field]; ok {
+				picked[field] = v
+			}
+		}
+		return picked
 	}
 
-	// terminal output niceness
-	j = append(j, '\n')
+	if bytes.HasPrefix(bytes.TrimSpace(j), []byte("[")) {
+		var objects []map[string]json.RawMessage
+		err = json.Unmarshal(j, &objects)
+		if err != nil {
+			return nil, err
+		}
 
-	// write headers
-	for key, value := range headers {
-		w.Header()[key] = value
+		for i := range objects {
+			objects[i] = pick(objects[i])
+		}
+		return objects, nil
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	w.Write(j)
+	var object map[string]json.RawMessage
+	err = json.Unmarshal(j, &object)
+	if err != nil {
+		return nil, err
+	}
 
-	return nil
+	return pick(object), nil
+}
+
+// movieETag returns the entity tag of a movie, derived from its version
+func movieETag(movie *data.Movie) string {
+	return strconv.Quote(fmt.Sprintf("%d-%d", movie.ID, movie.Version))
+}
+
+// formatETag returns the entity tag of a representation in the given format,
+// indented or not. compact JSON keeps the tag as it is, other formats get their
+// name appended and indented output gets "-pretty", so that caches and
+// conditional requests never mix up representations
+func formatETag(etag string, format responseFormat, pretty bool) string {
+	var suffix string
+	if format != formatJSON {
+		suffix += "-" + format.name
+	}
+	if pretty && (format == formatJSON || format == formatXML) {
+		suffix += "-pretty"
+	}
+
+	if suffix == "" {
+		return etag
+	}
+	return strings.TrimSuffix(etag, `"`) + suffix + `"`
+}
+
+// responseETag returns the entity tag of the representation negotiated from
+// the Accept header and ?pretty
+func responseETag(r *http.Request, etag string) string {
+	format, ok := negotiateFormat(r)
+	if !ok {
+		return etag
+	}
+	return formatETag(etag, format, readPretty(r))
+}
+
+// movieMatches reports whether an If-Match header value matches the current
+// version of a movie, in any of its representations
+func movieMatches(header string, movie *data.Movie) bool {
+	etag := movieETag(movie)
+
+	for _, format := range []responseFormat{formatJSON, formatXML, formatCSV, formatMsgPack} {
+		for _, pretty := range []bool{false, true} {
+			if etagMatches(header, formatETag(etag, format, pretty), false) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// envelopeETag returns an entity tag derived from the JSON of an envelope
+func envelopeETag(env envelope) (string, error) {
+	j, err := json.Marshal(env)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(j)
+	return strconv.Quote(hex.EncodeToString(sum[:16])), nil
+}
+
+// etagMatches reports whether an If-Match or If-None-Match header value
+// matches the entity tag. weak comparison ignores the W/ prefix
+func etagMatches(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+
+		if candidate == "*" {
+			return true
+		}
+
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+			etag = strings.TrimPrefix(etag, "W/")
+		} else if strings.HasPrefix(candidate, "W/") || strings.HasPrefix(etag, "W/") {
+			continue
+		}
+
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified sets the ETag header of the negotiated representation and, if
+// the client's If-None-Match header matches it, sends 304 Not Modified and
+// returns true
+func (app *application) notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	etag = responseETag(r, etag)
+	w.Header().Set("ETag", etag)
+
+	match := r.Header.Get("If-None-Match")
+	if match == "" || !etagMatches(match, etag, true) {
+		return false
+	}
+
+	// the tag depends on the format, so caches must keep one per Accept header
+	w.Header().Add("Vary", "Accept")
+	w.WriteHeader(http.StatusNotModified)
+	return true
 }
 
 // read json from request
@@ -98,49 +241,54 @@ func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any
 	// decode the request body to the destination
 	err := dec.Decode(dst)
 	if err != nil {
-		var syntaxError *json.SyntaxError
-		var unmarshalTypeError *json.UnmarshalTypeError
-		var invalidUnmarshalError *json.InvalidUnmarshalError
-		var maxBytesError *http.MaxBytesError
+		return decodeError(err)
+	}
 
-		switch {
-		case errors.As(err, &syntaxError):
-			return fmt.Errorf("body contains badly-formatted JSON (at character %d)", syntaxError.Offset)
+	// don't allow additional data on request body
+	err = dec.Decode(&struct{}{})
+	if !errors.Is(err, io.EOF) {
+		return errors.New("body must only contain a single JSON value")
+	}
 
-		case errors.Is(err, io.ErrUnexpectedEOF):
-			return errors.New("body contains badly-formatted JSON")
+	return nil
+}
 
-		case errors.As(err, &unmarshalTypeError):
-			if unmarshalTypeError.Field != "" {
-				return fmt.Errorf("body contains incorrect JSON type for field %q", unmarshalTypeError.Field)
-			}
-			return fmt.Errorf("body contains incorrect JSON type (at character %d)", unmarshalTypeError.Offset)
+// translate json decoding errors into messages fit for the client
+func decodeError(err error) error {
+	var syntaxError *json.SyntaxError
+	var unmarshalTypeError *json.UnmarshalTypeError
+	var invalidUnmarshalError *json.InvalidUnmarshalError
+	var maxBytesError *http.MaxBytesError
 
-		case errors.Is(err, io.EOF):
-			return errors.New("body must not be empty")
+	switch {
+	case errors.As(err, &syntaxError):
+		return fmt.Errorf("body contains badly-formatted JSON (at character %d)", syntaxError.Offset)
 
-		case strings.HasPrefix(err.Error(), "json: unknown field "):
-			fieldName := strings.TrimPrefix(err.Error(), "json: unknown field ")
-			return fmt.Errorf("body contains unknown key %s", fieldName)
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return errors.New("body contains badly-formatted JSON")
 
-		case errors.As(err, &maxBytesError):
-			return fmt.Errorf("body must not be larger than %d bytes", maxBytesError.Limit)
+	case errors.As(err, &unmarshalTypeError):
+		if unmarshalTypeError.Field != "" {
+			return fmt.Errorf("body contains incorrect JSON type for field %q", unmarshalTypeError.Field)
+		}
+		return fmt.Errorf("body contains incorrect JSON type (at character %d)", unmarshalTypeError.Offset)
 
-		case errors.As(err, &invalidUnmarshalError):
-			panic(err)
+	case errors.Is(err, io.EOF):
+		return errors.New("body must not be empty")
 
-		default:
-			return err
-		}
-	}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		fieldName := strings.TrimPrefix(err.Error(), "json: unknown field ")
+		return fmt.Errorf("body contains unknown key %s", fieldName)
 
-	// don't allow additional data on request body
-	err = dec.Decode(&struct{}{})
-	if !errors.Is(err, io.EOF) {
-		return errors.New("body must only contain a single JSON value")
-	}
+	case errors.As(err, &maxBytesError):
+		return fmt.Errorf("body must not be larger than %d bytes", maxBytesError.Limit)
 
-	return nil
+	case errors.As(err, &invalidUnmarshalError):
+		panic(err)
+
+	default:
+		return err
+	}
 }
 
 // arbitrary func helper to recover panics
@@ -163,3 +311,20 @@ func (app *application) background(fn func()) {
 		fn()
 	}()
 }
+
+// run fn every interval in the background, until the server shuts down
+func (app *application) periodic(interval time.Duration, fn func()) {
+	app.background(func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				fn()
+			case <-app.shutdown:
+				return
+			}
+		}
+	})
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ildx/greenlight/internal/data"
+)
+
+// every representation of a resource has an entity tag of its own
+func TestNotModified(t *testing.T) {
+	const etag = `"1-2"`
+
+	tests := []struct {
+		name        string
+		target      string
+		accept      string
+		ifNoneMatch string
+		wantETag    string
+		wantMatch   bool
+	}{
+		{"no If-None-Match", "/v1/movies/1", "", "", `"1-2"`, false},
+		{"compact JSON", "/v1/movies/1", "", `"1-2"`, `"1-2"`, true},
+		{"weak compact JSON", "/v1/movies/1", "", `W/"1-2"`, `"1-2"`, true},
+		{"stale", "/v1/movies/1", "", `"1-1"`, `"1-2"`, false},
+		{"pretty JSON", "/v1/movies/1?pretty", "", `"1-2-pretty"`, `"1-2-pretty"`, true},
+		{"pretty JSON with the compact tag", "/v1/movies/1?pretty=true", "", `"1-2"`, `"1-2-pretty"`, false},
+		{"compact JSON with the pretty tag", "/v1/movies/1?pretty=false", "", `"1-2-pretty"`, `"1-2"`, false},
+		{"XML", "/v1/movies/1", "application/xml", `"1-2-xml"`, `"1-2-xml"`, true},
+		{"pretty XML", "/v1/movies/1?pretty", "application/xml", `"1-2-xml-pretty"`, `"1-2-xml-pretty"`, true},
+		{"pretty XML with the compact tag", "/v1/movies/1?pretty", "application/xml", `"1-2-xml"`, `"1-2-xml-pretty"`, false},
+		{"MessagePack ignores pretty", "/v1/movies/1?pretty", "application/vnd.msgpack", `"1-2-msgpack"`, `"1-2-msgpack"`, true},
+		{"XML with the JSON tag", "/v1/movies/1", "application/xml", `"1-2"`, `"1-2-xml"`, false},
+		{"one of many", "/v1/movies/1?pretty", "", `"1-1", "1-2-pretty"`, `"1-2-pretty"`, true},
+		{"any", "/v1/movies/1?pretty", "", `*`, `"1-2-pretty"`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			if tt.ifNoneMatch != "" {
+				r.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+
+			w := httptest.NewRecorder()
+
+			app := &application{}
+			match := app.notModified(w, r, etag)
+
+			if match != tt.wantMatch {
+				t.Errorf("got match %t, want %t", match, tt.wantMatch)
+			}
+
+			if match && w.Code != http.StatusNotModified {
+				t.Errorf("got status %d, want %d", w.Code, http.StatusNotModified)
+			}
+
+			if got := w.Header().Get("ETag"); got != tt.wantETag {
+				t.Errorf("got ETag %s, want %s", got, tt.wantETag)
+			}
+		})
+	}
+}
+
+// If-Match accepts the tag of any representation of the current version
+func TestMovieMatches(t *testing.T) {
+	movie := &data.Movie{ID: 1, Version: 2}
+
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{`"1-2"`, true},
+		{`"1-2-pretty"`, true},
+		{`"1-2-xml"`, true},
+		{`"1-2-xml-pretty"`, true},
+		{`"1-2-csv"`, true},
+		{`"1-2-msgpack"`, true},
+		{`"1-1"`, false},
+		{`"1-1-pretty"`, false},
+		{`W/"1-2"`, false},
+		{`"1-1", "1-2-pretty"`, true},
+		{`*`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if got := movieMatches(tt.header, movie); got != tt.want {
+				t.Errorf("got %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/ildx/greenlight/internal/i18n"
+	"github.com/ildx/greenlight/internal/importer"
+	"github.com/ildx/greenlight/internal/validator"
+)
+
+// post /v1/movies/import
+func (app *application) importMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	qs := r.URL.Query()
+
+	// the format comes from ?format, or else from the Content-Type
+	format := app.readString(qs, "format", "")
+	if format == "" {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+		switch mediaType {
+		case "text/csv":
+			format = importer.FormatCSV
+		case "application/x-ndjson", "application/ndjson":
+			format = importer.FormatNDJSON
+		}
+	}
+
+	dryRun := app.readBool(qs, "dry_run", false, v)
+	batchSize := app.readInt(qs, "batch_size", 500, v)
+
+	v.Check(validator.PermittedValue(format, importer.FormatCSV, importer.FormatNDJSON), "format", validator.CodeNotPermitted, "must be csv or ndjson")
+	v.Check(batchSize > 0 && batchSize <= 5000, "batch_size", validator.CodeOutOfRange, "must be between 1 and 5000", 1, 5000)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	// imports outlive the server's ReadTimeout and WriteTimeout, so they get
+	// deadlines of their own
+	deadline := time.Now().Add(app.config.imports.timeout)
+
+	rc := http.NewResponseController(w)
+
+	err := rc.SetReadDeadline(deadline)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = rc.SetWriteDeadline(deadline)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// limit the size of the request body to 32MB. the importer reads all of it
+	// before inserting anything, so a body that is too large imports nothing
+	r.Body = http.MaxBytesReader(w, r.Body, 32<<20)
+
+	// the row errors in the report are in the negotiated language
+	lang := i18n.Negotiate(r.Header.Get("Accept-Language"))
+
+	// every batch is committed on its own, so the ones committed before a
+	// failure stay committed and the report tells which rows made it
+	report, err := importer.New(app.models, app.logger).Run(r.Body, importer.Options{
+		Format:    format,
+		DryRun:    dryRun,
+		BatchSize: batchSize,
+		EditorID:  app.contextGetUser(r).ID,
+		Language:  lang,
+	})
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+
+		switch {
+		case errors.Is(err, importer.ErrInvalidInput):
+			app.badRequestResponse(w, r, err)
+		case errors.As(err, &maxBytesError):
+			app.badRequestResponse(w, r, decodeError(err))
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	status := http.StatusOK
+	if report.Created > 0 {
+		status = http.StatusCreated
+	}
+
+	w.Header().Set("Content-Language", lang)
+	w.Header().Add("Vary", "Accept-Language")
+
+	err = app.render(w, r, status, envelope{"report": report}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
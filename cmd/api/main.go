@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
 	"database/sql"
+	"encoding/hex"
 	"expvar"
 	"flag"
 	"fmt"
@@ -34,11 +36,14 @@ type config struct {
 		maxOpenConns int
 		maxIdleConns int
 		maxIdleTime  time.Duration
+		automigrate  bool // apply pending migrations on startup
 	}
 	limiter struct {
-		rps     float64 // requests per second
-		burst   int
-		enabled bool
+		rps          float64 // requests per second
+		burst        int
+		enabled      bool
+		suggestRPS   float64 // requests per second for title suggestions
+		suggestBurst int
 	}
 	smtp struct {
 		host     string
@@ -50,16 +55,37 @@ type config struct {
 	cors struct {
 		trustedOrigins []string
 	}
+	cursor struct {
+		secret string
+	}
+	trash struct {
+		retention     time.Duration
+		purgeInterval time.Duration // 0 disables the purge
+	}
+	tokens struct {
+		cleanupInterval  time.Duration // 0 disables the cleanup
+		cleanupBatchSize int
+	}
+	export struct {
+		writeTimeout time.Duration
+	}
+	imports struct {
+		timeout time.Duration // read and write timeout, which replaces the server's
+	}
+	errors struct {
+		problemDetails bool // always send errors as RFC 9457 problem details
+	}
 }
 
 // application struct to hold the dependencies
 // for the HTTP handlers, helpers, and middleware
 type application struct {
-	config config
-	logger *slog.Logger
-	models data.Models
-	mailer mailer.Mailer
-	wg     sync.WaitGroup
+	config   config
+	logger   *slog.Logger
+	models   data.Models
+	mailer   mailer.Mailer
+	wg       sync.WaitGroup
+	shutdown chan struct{} // closed when the server starts shutting down
 }
 
 func main() {
@@ -80,15 +106,20 @@ func main() {
 	flag.IntVar(&cfg.port, "port", 4000, "API server port")
 	flag.StringVar(&cfg.env, "env", "development", "Environment (development|staging|production)")
 
+	flag.BoolVar(&cfg.errors.problemDetails, "problem-details", false, "Always send errors as RFC 9457 problem details")
+
 	flag.StringVar(&cfg.db.dsn, "db-dsn", "", "PostgreSQL DSN")
 
 	flag.IntVar(&cfg.db.maxOpenConns, "db-max-open-conns", 25, "PostgreSQL max open connections")
 	flag.IntVar(&cfg.db.maxIdleConns, "db-max-idle-conns", 25, "PostgreSQL max idle connections")
 	flag.DurationVar(&cfg.db.maxIdleTime, "db-max-idle-time", 15*time.Minute, "PostgreSQL max connection idle time")
+	flag.BoolVar(&cfg.db.automigrate, "db-automigrate", false, "Apply pending database migrations on startup")
 
 	flag.Float64Var(&cfg.limiter.rps, "limiter-rps", 2, "Rate limiter maximum requests per second")
 	flag.IntVar(&cfg.limiter.burst, "limiter-burst", 4, "Rate limiter maximum burst")
 	flag.BoolVar(&cfg.limiter.enabled, "limiter-enabled", true, "Enable rate limiter")
+	flag.Float64Var(&cfg.limiter.suggestRPS, "limiter-suggest-rps", 10, "Rate limiter maximum requests per second for title suggestions")
+	flag.IntVar(&cfg.limiter.suggestBurst, "limiter-suggest-burst", 20, "Rate limiter maximum burst for title suggestions")
 
 	smtpPort, err := strconv.Atoi(os.Getenv("SMTP_PORT"))
 	if err != nil {
@@ -107,6 +138,17 @@ func main() {
 		return nil
 	})
 
+	flag.StringVar(&cfg.cursor.secret, "cursor-secret", os.Getenv("CURSOR_SECRET"), "Secret for signing pagination cursors")
+
+	flag.DurationVar(&cfg.trash.retention, "trash-retention", 30*24*time.Hour, "How long deleted movies are kept before they're purged")
+	flag.DurationVar(&cfg.trash.purgeInterval, "trash-purge-interval", time.Hour, "How often to purge expired movies from the trash; 0 disables it")
+
+	flag.DurationVar(&cfg.tokens.cleanupInterval, "tokens-cleanup-interval", time.Hour, "How often to delete expired tokens; 0 disables it")
+	flag.IntVar(&cfg.tokens.cleanupBatchSize, "tokens-cleanup-batch-size", 1000, "Expired tokens deleted per transaction")
+
+	flag.DurationVar(&cfg.export.writeTimeout, "export-write-timeout", 10*time.Minute, "Write timeout for catalogue exports, which replaces the server's")
+	flag.DurationVar(&cfg.imports.timeout, "import-timeout", 10*time.Minute, "Read and write timeout for movie imports, which replaces the server's")
+
 	displayVersion := flag.Bool("version", false, "Display version and exit")
 
 	flag.Parse()
@@ -116,6 +158,28 @@ func main() {
 		os.Exit(0)
 	}
 
+	if cfg.trash.purgeInterval < 0 {
+		logger.Error("-trash-purge-interval must not be negative")
+		os.Exit(1)
+	}
+
+	if cfg.tokens.cleanupBatchSize < 1 {
+		logger.Error("-tokens-cleanup-batch-size must be at least 1")
+		os.Exit(1)
+	}
+
+	// without a configured secret, cursors only stay valid until the next restart
+	if cfg.cursor.secret == "" {
+		secret := make([]byte, 32)
+		_, err := rand.Read(secret)
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+		cfg.cursor.secret = hex.EncodeToString(secret)
+		logger.Warn("no cursor secret configured, using a random one")
+	}
+
 	// connect to the database
 	db, err := openDB(cfg)
 	if err != nil {
@@ -125,6 +189,24 @@ func main() {
 	defer db.Close()
 	logger.Info("database connection pool established")
 
+	// api migrate <command> manages the migrations instead of serving
+	if flag.Arg(0) == "migrate" {
+		err = runMigrate(db, logger, flag.Args()[1:])
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	if cfg.db.automigrate {
+		err = autoMigrate(db, logger)
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+	}
+
 	// publish "version" variable to expvar
 	expvar.NewString("versions").Set(version)
 
@@ -145,10 +227,30 @@ func main() {
 
 	// declare app instance
 	app := &application{
-		config: cfg,
-		logger: logger,
-		models: data.NewModels(db),
-		mailer: mailer.New(cfg.smtp.host, cfg.smtp.port, cfg.smtp.username, cfg.smtp.password, cfg.smtp.sender),
+		config:   cfg,
+		logger:   logger,
+		models:   data.NewModels(db),
+		mailer:   mailer.New(cfg.smtp.host, cfg.smtp.port, cfg.smtp.username, cfg.smtp.password, cfg.smtp.sender),
+		shutdown: make(chan struct{}),
+	}
+
+	// permanently delete movies once they've been in the trash long enough
+	if cfg.trash.purgeInterval > 0 {
+		app.periodic(cfg.trash.purgeInterval, func() {
+			purged, err := app.models.Movies.Purge(cfg.trash.retention)
+			if err != nil {
+				app.logger.Error(err.Error())
+				return
+			}
+			if purged > 0 {
+				app.logger.Info("purged movies from the trash", "count", purged)
+			}
+		})
+	}
+
+	// delete expired tokens, which are otherwise kept forever
+	if cfg.tokens.cleanupInterval > 0 {
+		app.periodic(cfg.tokens.cleanupInterval, app.deleteExpiredTokens)
 	}
 
 	err = app.serve()
@@ -74,6 +74,12 @@ func (app *application) recoverPanic(next http.Handler) http.Handler {
 		defer func() {
 			// use built-in recover function to catch and handle panics
 			if err := recover(); err != nil {
+				// handlers abort responses that are already under way with
+				// http.ErrAbortHandler; let the server drop the connection
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+
 				// if panic, set "Connection: close" header.
 				// this will auto-close current connection after response is sent
 				w.Header().Set("Connection", "close")
@@ -93,7 +99,19 @@ func (app *application) rateLimit(next http.Handler) http.Handler {
 		lastSeen time.Time
 	}
 
-	// define mutex and map to store client ips and their rate limits
+	// bucket struct to hold the limits for a group of routes
+	type bucket struct {
+		rps   float64
+		burst int
+	}
+
+	// routes with their own, looser limits; every other route
+	// shares the default bucket
+	buckets := map[string]bucket{
+		"/v1/movies/suggest": {rps: app.config.limiter.suggestRPS, burst: app.config.limiter.suggestBurst},
+	}
+
+	// define mutex and map to store the rate limits per client ip and bucket
 	var (
 		mu      sync.Mutex
 		clients = make(map[string]*client)
@@ -108,9 +126,9 @@ func (app *application) rateLimit(next http.Handler) http.Handler {
 			mu.Lock()
 
 			// loop clients; if no activity in last 3 minutes, delete the entry
-			for ip, client := range clients {
+			for key, client := range clients {
 				if time.Since(client.lastSeen) > 3*time.Minute {
-					delete(clients, ip)
+					delete(clients, key)
 				}
 			}
 
@@ -125,22 +143,31 @@ func (app *application) rateLimit(next http.Handler) http.Handler {
 			// get the client ip address
 			ip := realip.FromRequest(r)
 
+			// pick the bucket for the route; clients are tracked per bucket
+			key := ip
+			limits := bucket{rps: app.config.limiter.rps, burst: app.config.limiter.burst}
+
+			if b, ok := buckets[r.URL.Path]; ok {
+				key = r.URL.Path + " " + ip
+				limits = b
+			}
+
 			// lock to prevent concurrent access to the map
 			mu.Lock()
 
-			// check if ip is already in map. if not,
-			// init new rate limiter and add it with the ip to the map
-			if _, found := clients[ip]; !found {
-				clients[ip] = &client{
-					limiter: rate.NewLimiter(rate.Limit(app.config.limiter.rps), app.config.limiter.burst),
+			// check if client is already in map. if not,
+			// init new rate limiter and add it to the map
+			if _, found := clients[key]; !found {
+				clients[key] = &client{
+					limiter: rate.NewLimiter(rate.Limit(limits.rps), limits.burst),
 				}
 			}
 
 			// update the last seen time for the client
-			clients[ip].lastSeen = time.Now()
+			clients[key].lastSeen = time.Now()
 
 			// check if the rate limiter allows the request
-			if !clients[ip].limiter.Allow() {
+			if !clients[key].limiter.Allow() {
 				mu.Unlock()
 				app.rateLimitExceededResponse(w, r)
 				return
@@ -220,11 +247,12 @@ func (app *application) enableCORS(next http.Handler) http.Handler {
 			for i := range app.config.cors.trustedOrigins {
 				if origin == app.config.cors.trustedOrigins[i] {
 					w.Header().Set("Access-Control-Allow-Origin", origin)
+					w.Header().Set("Access-Control-Expose-Headers", "ETag")
 
 					// preflight and options
 					if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
 						w.Header().Set("Access-Control-Allow-Methods", "OPTIONS, PUT, PATCH, DELETE")
-						w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+						w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, If-Match, If-None-Match")
 						w.WriteHeader(http.StatusOK)
 						return
 					}
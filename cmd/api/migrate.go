@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/ildx/greenlight/internal/migrate"
+	"github.com/ildx/greenlight/migrations"
+)
+
+const migrateUsage = `usage: api [flags] migrate <command>
+
+commands:
+  up [N]       apply all pending migrations, or the next N
+  down [N]     revert the last migration, or the last N
+  goto V       migrate up or down to version V, or revert everything for 0
+  status       show the version of the database and the migrations
+  force V      set the version without migrating, after fixing a failed migration`
+
+// runMigrate runs a migration command against the embedded migrations
+func runMigrate(db *sql.DB, logger *slog.Logger, args []string) error {
+	migrator, err := migrate.New(db, migrations.FS, logger)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return errors.New("missing migrate command")
+	}
+
+	// the optional or required numeric argument of a command
+	number := func(defaultValue int) (int, error) {
+		if len(args) < 2 {
+			if defaultValue < 0 {
+				return 0, fmt.Errorf("migrate %s needs a version", args[0])
+			}
+			return defaultValue, nil
+		}
+
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid number %q", args[1])
+		}
+		return n, nil
+	}
+
+	var steps, n int
+
+	switch args[0] {
+	case "up":
+		n, err = number(0)
+		if err != nil {
+			return err
+		}
+		steps, err = migrator.Up(ctx, n)
+
+	case "down":
+		n, err = number(1)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return errors.New("migrate down needs a positive number; use goto 0 to revert everything")
+		}
+		steps, err = migrator.Down(ctx, n)
+
+	case "goto":
+		n, err = number(-1)
+		if err != nil {
+			return err
+		}
+		steps, err = migrator.Goto(ctx, n)
+
+	case "force":
+		n, err = number(-1)
+		if err != nil {
+			return err
+		}
+		return migrator.Force(ctx, n)
+
+	case "status":
+		return printMigrationStatus(ctx, migrator)
+
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+
+	if errors.Is(err, migrate.ErrNoChange) {
+		logger.Info("database is up to date")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	logger.Info("migrated database", "migrations", steps)
+	return nil
+}
+
+// autoMigrate applies pending migrations on startup. instances starting at the
+// same time wait for each other, so only the first one migrates
+func autoMigrate(db *sql.DB, logger *slog.Logger) error {
+	migrator, err := migrate.New(db, migrations.FS, logger)
+	if err != nil {
+		return err
+	}
+
+	steps, err := migrator.Up(context.Background(), 0)
+	switch {
+	case errors.Is(err, migrate.ErrNoChange):
+		return nil
+	case err != nil:
+		return err
+	}
+
+	logger.Info("migrated database", "migrations", steps)
+	return nil
+}
+
+func printMigrationStatus(ctx context.Context, migrator *migrate.Migrator) error {
+	status, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	if status.Dirty {
+		fmt.Printf("version %d (dirty)\n\n", status.Version)
+	} else {
+		fmt.Printf("version %d\n\n", status.Version)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED")
+
+	for _, m := range status.Migrations {
+		fmt.Fprintf(tw, "%d\t%s\t%t\n", m.Version, m.Name, m.Applied)
+	}
+
+	return tw.Flush()
+}
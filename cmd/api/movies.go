@@ -4,11 +4,61 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"slices"
+	"strings"
 
 	"github.com/ildx/greenlight/internal/data"
 	"github.com/ildx/greenlight/internal/validator"
 )
 
+// the writable fields of a movie, as patched by merge patches and JSON patches
+type movieFields struct {
+	Title   string       `json:"title,omitempty"`
+	Year    int32        `json:"year,omitempty"`
+	Runtime data.Runtime `json:"runtime,omitempty"`
+	Genres  []string     `json:"genres,omitempty"`
+}
+
+// readMovieFilter reads the search and filter parameters shared by the movie listing and the export
+func (app *application) readMovieFilter(qs url.Values, v *validator.Validator) data.MovieFilter {
+	var filter data.MovieFilter
+
+	filter.Title = app.readString(qs, "title", "")
+	filter.SearchMode = app.readString(qs, "search_mode", data.SearchModePlain)
+	filter.Language = app.readString(qs, "lang", "simple")
+
+	// genres prefixed with "-" are excluded, e.g. ?genres=drama,-horror
+	for _, genre := range app.readCSV(qs, "genres", []string{}) {
+		if excluded, ok := strings.CutPrefix(genre, "-"); ok {
+			filter.ExcludeGenres = append(filter.ExcludeGenres, excluded)
+		} else {
+			filter.Genres = append(filter.Genres, genre)
+		}
+	}
+
+	filter.GenreMode = app.readString(qs, "genre_mode", data.GenreModeAll)
+	filter.YearFrom = app.readInt(qs, "year_from", 0, v)
+	filter.YearTo = app.readInt(qs, "year_to", 0, v)
+	filter.RuntimeMin = app.readInt(qs, "runtime_min", 0, v)
+	filter.RuntimeMax = app.readInt(qs, "runtime_max", 0, v)
+
+	return filter
+}
+
+// filter on canonical slugs so that aliases match too
+func (app *application) canonicalizeMovieFilter(filter *data.MovieFilter) error {
+	taxonomy, err := app.models.Genres.Taxonomy()
+	if err != nil {
+		return err
+	}
+
+	filter.Genres = taxonomy.Canonicalize(filter.Genres)
+	filter.ExcludeGenres = taxonomy.Canonicalize(filter.ExcludeGenres)
+
+	return nil
+}
+
 // post /v1/movies
 func (app *application) createMovieHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
@@ -32,15 +82,25 @@ func (app *application) createMovieHandler(w http.ResponseWriter, r *http.Reques
 		Genres:  input.Genres,
 	}
 
+	// map genre names and aliases onto the canonical slugs
+	taxonomy, err := app.models.Genres.Taxonomy()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	movie.Genres = taxonomy.Canonicalize(movie.Genres)
+
 	v := validator.New()
+	v.CollectAll = true
 
-	if data.ValidateMovie(v, movie); !v.Valid() {
-		app.failedValidationResponse(w, r, v.Errors)
+	if data.ValidateMovie(v, movie, taxonomy); !v.Valid() {
+		app.failedValidationResponse(w, r, v)
 		return
 	}
 
 	// insert the movie into the database
-	err = app.models.Movies.Insert(movie)
+	err = app.models.Movies.Insert(movie, app.contextGetUser(r).ID)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -51,7 +111,7 @@ func (app *application) createMovieHandler(w http.ResponseWriter, r *http.Reques
 	headers := make(http.Header)
 	headers.Set("Location", fmt.Sprintf("/v1/movies/%d", movie.ID))
 
-	err = app.writeJSON(w, http.StatusCreated, envelope{"movie": movie}, headers)
+	err = app.render(w, r, http.StatusCreated, envelope{"movie": movie}, headers)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -65,7 +125,18 @@ func (app *application) showMovieHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	movie, err := app.models.Movies.Get(id)
+	v := validator.New()
+	qs := r.URL.Query()
+
+	fields := app.readCSV(qs, "fields", []string{})
+	include := app.readCSV(qs, "include", []string{})
+
+	if data.ValidateMovieFields(v, fields, include); !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	movie, err := app.models.Movies.GetFields(id, movieSelectFields(fields, include))
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -76,7 +147,28 @@ func (app *application) showMovieHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"movie": movie}, nil)
+	env, err := app.movieEnvelope("movie", movie, fields, include, movie)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// the full movie is tagged by its version; trimmed or embellished
+	// representations are tagged by their content
+	etag := movieETag(movie)
+	if len(fields) > 0 || len(include) > 0 {
+		etag, err = envelopeETag(env)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	if app.notModified(w, r, etag) {
+		return
+	}
+
+	err = app.render(w, r, http.StatusOK, env, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -100,45 +192,85 @@ func (app *application) updateMovieHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	var input struct {
-		Title   *string       `json:"title"`
-		Year    *int32        `json:"year"`
-		Runtime *data.Runtime `json:"runtime"`
-		Genres  []string      `json:"genres"`
-	}
-
-	err = app.readJSON(w, r, &input)
-	if err != nil {
-		app.badRequestResponse(w, r, err)
+	// clients that send If-Match must have seen the latest version
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch != "" && !movieMatches(ifMatch, movie) {
+		app.preconditionFailedResponse(w, r)
 		return
 	}
 
-	if input.Title != nil {
-		movie.Title = *input.Title
-	}
+	// merge patches and JSON patches can also clear fields,
+	// plain JSON bodies only overwrite the fields they contain
+	if isPatchRequest(r) {
+		fields := movieFields{
+			Title:   movie.Title,
+			Year:    movie.Year,
+			Runtime: movie.Runtime,
+			Genres:  movie.Genres,
+		}
 
-	if input.Year != nil {
-		movie.Year = *input.Year
-	}
+		err = app.readPatch(w, r, &fields)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		movie.Title = fields.Title
+		movie.Year = fields.Year
+		movie.Runtime = fields.Runtime
+		movie.Genres = fields.Genres
+	} else {
+		var input struct {
+			Title   *string       `json:"title"`
+			Year    *int32        `json:"year"`
+			Runtime *data.Runtime `json:"runtime"`
+			Genres  []string      `json:"genres"`
+		}
+
+		err = app.readJSON(w, r, &input)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		if input.Title != nil {
+			movie.Title = *input.Title
+		}
+
+		if input.Year != nil {
+			movie.Year = *input.Year
+		}
+
+		if input.Runtime != nil {
+			movie.Runtime = *input.Runtime
+		}
 
-	if input.Runtime != nil {
-		movie.Runtime = *input.Runtime
+		if input.Genres != nil {
+			movie.Genres = input.Genres
+		}
 	}
 
-	if input.Genres != nil {
-		movie.Genres = input.Genres
+	taxonomy, err := app.models.Genres.Taxonomy()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
 	}
 
+	movie.Genres = taxonomy.Canonicalize(movie.Genres)
+
 	v := validator.New()
+	v.CollectAll = true
 
-	if data.ValidateMovie(v, movie); !v.Valid() {
-		app.failedValidationResponse(w, r, v.Errors)
+	if data.ValidateMovie(v, movie, taxonomy); !v.Valid() {
+		app.failedValidationResponse(w, r, v)
 		return
 	}
 
-	err = app.models.Movies.Update(movie)
+	err = app.models.Movies.Update(movie, app.contextGetUser(r).ID)
 	if err != nil {
 		switch {
+		case errors.Is(err, data.ErrEditConflict) && ifMatch != "":
+			app.preconditionFailedResponse(w, r)
 		case errors.Is(err, data.ErrEditConflict):
 			app.editConflictResponse(w, r)
 		default:
@@ -147,7 +279,10 @@ func (app *application) updateMovieHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"movie": movie}, nil)
+	headers := make(http.Header)
+	headers.Set("ETag", responseETag(r, movieETag(movie)))
+
+	err = app.render(w, r, http.StatusOK, envelope{"movie": movie}, headers)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -160,18 +295,43 @@ func (app *application) deleteMovieHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	err = app.models.Movies.Delete(id)
+	// with If-Match, only delete the version the client has seen
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch != "" {
+		movie, err := app.models.Movies.Get(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		if !movieMatches(ifMatch, movie) {
+			app.preconditionFailedResponse(w, r)
+			return
+		}
+
+		err = app.models.Movies.DeleteVersion(id, movie.Version, app.contextGetUser(r).ID)
+	} else {
+		err = app.models.Movies.Delete(id, app.contextGetUser(r).ID)
+	}
+
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
 			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrEditConflict):
+			app.preconditionFailedResponse(w, r)
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"message": "movie successfully deleted!"}, nil)
+	err = app.render(w, r, http.StatusOK, envelope{"message": "movie moved to the trash!"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -179,34 +339,226 @@ func (app *application) deleteMovieHandler(w http.ResponseWriter, r *http.Reques
 
 func (app *application) listMoviesHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		Title  string
-		Genres []string
+		data.MovieFilter
+		Facets []string
 		data.Filters
 	}
 
 	v := validator.New()
 	qs := r.URL.Query()
 
-	input.Title = app.readString(qs, "title", "")
-	input.Genres = app.readCSV(qs, "genres", []string{})
+	input.MovieFilter = app.readMovieFilter(qs, v)
+
+	input.Facets = app.readCSV(qs, "facets", []string{})
+
+	fields := app.readCSV(qs, "fields", []string{})
+	include := app.readCSV(qs, "include", []string{})
 
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
 	input.Filters.Sort = app.readString(qs, "sort", "id")
-	input.Filters.SortSafeList = []string{"id", "title", "year", "runtime", "-id", "-title", "-year", "-runtime"}
+	input.Filters.SortSafeList = []string{"id", "title", "year", "runtime", "relevance", "-id", "-title", "-year", "-runtime"}
+
+	// ?pagination=cursor starts keyset pagination, and later pages pass the
+	// cursors from the metadata; page numbers keep working as before
+	input.Filters.Cursor = app.readString(qs, "cursor", "")
+	input.Filters.UseCursor = input.Filters.Cursor != "" || app.readString(qs, "pagination", "page") == "cursor"
+	input.Filters.CursorKey = []byte(app.config.cursor.secret)
+	input.Filters.IncludeTotal = app.readBool(qs, "include_total", false, v)
+
+	if input.Filters.UseCursor {
+		v.Check(input.Filters.Sort != "relevance", "sort", validator.CodeConflict, "relevance can't be combined with cursor pagination")
+	}
+
+	err := app.canonicalizeMovieFilter(&input.MovieFilter)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	data.ValidateMovieFilter(v, input.MovieFilter)
+	data.ValidateFacets(v, input.Facets)
+	data.ValidateMovieFields(v, fields, include)
+
+	input.Filters.Fields = movieSelectFields(fields, include)
 
 	if data.ValidateFilters(v, input.Filters); !v.Valid() {
-		app.failedValidationResponse(w, r, v.Errors)
+		app.failedValidationResponse(w, r, v)
 		return
 	}
 
-	movies, metadata, err := app.models.Movies.GetAll(input.Title, input.Genres, input.Filters)
+	movies, metadata, err := app.models.Movies.GetAll(input.MovieFilter, input.Filters)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"movies": movies, "metadata": metadata}, nil)
+	env, err := app.movieEnvelope("movies", movies, fields, include, movies...)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	env["metadata"] = metadata
+
+	// facets are only computed when asked for
+	if len(input.Facets) > 0 {
+		facets, err := app.models.Movies.Facets(input.MovieFilter, input.Facets)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		env["facets"] = facets
+	}
+
+	etag, err := envelopeETag(env)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if app.notModified(w, r, etag) {
+		return
+	}
+
+	err = app.render(w, r, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// get /v1/movies/suggest
+func (app *application) suggestMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	qs := r.URL.Query()
+
+	q := app.readString(qs, "q", "")
+	limit := app.readInt(qs, "limit", 10, v)
+
+	if data.ValidateSuggest(v, q, limit); !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	suggestions, err := app.models.Movies.Suggest(q, limit)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.render(w, r, http.StatusOK, envelope{"suggestions": suggestions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// the fields to load for a movie: the requested ones, plus
+// whatever the related data in include depends on
+func movieSelectFields(fields, include []string) []string {
+	if len(fields) > 0 && slices.Contains(include, "genres") && !slices.Contains(fields, "genres") {
+		return append(slices.Clip(fields), "genres")
+	}
+	return fields
+}
+
+// movieEnvelope wraps a movie or a list of movies under key, trimmed down to
+// the requested fields, and with the requested related data under "included"
+func (app *application) movieEnvelope(key string, value any, fields, include []string, movies ...*data.Movie) (envelope, error) {
+	projected, err := app.project(value, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	env := envelope{key: projected}
+
+	if len(include) == 0 {
+		return env, nil
+	}
+
+	included := map[string]any{}
+
+	if slices.Contains(include, "genres") {
+		var slugs []string
+		for _, movie := range movies {
+			for _, genre := range movie.Genres {
+				if !slices.Contains(slugs, genre) {
+					slugs = append(slugs, genre)
+				}
+			}
+		}
+
+		genres, err := app.models.Genres.GetBySlugs(slugs)
+		if err != nil {
+			return nil, err
+		}
+		included["genres"] = genres
+	}
+
+	env["included"] = included
+
+	return env, nil
+}
+
+// get /v1/movies/trash
+func (app *application) listTrashHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		data.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = "-deleted_at"
+	input.Filters.SortSafeList = []string{"-deleted_at"}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	movies, metadata, err := app.models.Movies.GetTrash(input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	env := envelope{
+		"movies":    movies,
+		"metadata":  metadata,
+		"retention": app.config.trash.retention.String(),
+	}
+
+	err = app.render(w, r, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// post /v1/movies/:id/restore
+func (app *application) restoreMovieHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	movie, err := app.models.Movies.Restore(id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("ETag", responseETag(r, movieETag(movie)))
+
+	err = app.render(w, r, http.StatusOK, envelope{"movie": movie}, headers)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
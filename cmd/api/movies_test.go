@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/ildx/greenlight/internal/data"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// clients opting into problem details still get JSON for successful responses
+func TestShowMovieAcceptProblemJSON(t *testing.T) {
+	app := newTestApplication(t)
+
+	movie := &data.Movie{Title: "Moana", Year: 2016, Runtime: 107, Genres: []string{"animation", "adventure"}}
+
+	err := app.models.Movies.Insert(movie, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id := strconv.FormatInt(movie.ID, 10)
+
+	r := httptest.NewRequest(http.MethodGet, "/v1/movies/"+id, nil)
+	r.Header.Set("Accept", "application/problem+json")
+
+	w := app.testRequest(t, app.showMovieHandler, r, httprouter.Params{{Key: "id", Value: id}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", w.Code, http.StatusOK)
+	}
+
+	if got := w.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("got Content-Type %q, want %q", got, "application/json")
+	}
+
+	var env struct {
+		Movie data.Movie `json:"movie"`
+	}
+
+	err = json.Unmarshal(w.Body.Bytes(), &env)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if env.Movie.ID != movie.ID {
+		t.Errorf("got movie %d, want %d", env.Movie.ID, movie.ID)
+	}
+}
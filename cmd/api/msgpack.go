@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// appendMsgPack appends the MessagePack encoding of a document, as returned by
+// toDocument. only the types JSON can express are needed: nil, booleans,
+// numbers, strings, arrays and maps
+func appendMsgPack(b []byte, value any) ([]byte, error) {
+	var err error
+
+	switch value := value.(type) {
+	case nil:
+		return append(b, 0xc0), nil
+
+	case bool:
+		if value {
+			return append(b, 0xc3), nil
+		}
+		return append(b, 0xc2), nil
+
+	case json.Number:
+		if n, err := value.Int64(); err == nil {
+			return appendMsgPackInt(b, n), nil
+		}
+
+		f, err := value.Float64()
+		if err != nil {
+			return nil, err
+		}
+
+		b = append(b, 0xcb)
+		return binary.BigEndian.AppendUint64(b, math.Float64bits(f)), nil
+
+	case string:
+		b = appendMsgPackHeader(b, len(value), 0xa0, 32, 0xd9, 0xda, 0xdb)
+		return append(b, value...), nil
+
+	case []any:
+		b = appendMsgPackHeader(b, len(value), 0x90, 16, 0, 0xdc, 0xdd)
+		for _, v := range value {
+			b, err = appendMsgPack(b, v)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+
+	case object:
+		b = appendMsgPackHeader(b, len(value), 0x80, 16, 0, 0xde, 0xdf)
+		for _, m := range value {
+			b, _ = appendMsgPack(b, m.Key)
+			b, err = appendMsgPack(b, m.Value)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+
+	default:
+		return nil, fmt.Errorf("msgpack: unsupported type %T", value)
+	}
+}
+
+// appendMsgPackInt uses the smallest integer encoding that fits n
+func appendMsgPackInt(b []byte, n int64) []byte {
+	switch {
+	case n >= 0 && n <= math.MaxInt8:
+		return append(b, byte(n)) // positive fixint
+	case n < 0 && n >= -32:
+		return append(b, byte(n)) // negative fixint
+	case n >= 0 && n <= math.MaxUint8:
+		return append(b, 0xcc, byte(n))
+	case n >= 0 && n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xcd), uint16(n))
+	case n >= 0 && n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, 0xce), uint32(n))
+	case n >= 0:
+		return binary.BigEndian.AppendUint64(append(b, 0xcf), uint64(n))
+	case n >= math.MinInt8:
+		return append(b, 0xd0, byte(n))
+	case n >= math.MinInt16:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(n))
+	case n >= math.MinInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(n))
+	}
+}
+
+// appendMsgPackHeader appends the type and length of a string, array or map:
+// the fix variant for lengths below fixMax, otherwise the 8 (strings only),
+// 16 or 32 bit variant
+func appendMsgPackHeader(b []byte, n int, fix byte, fixMax int, code8, code16, code32 byte) []byte {
+	switch {
+	case n < fixMax:
+		return append(b, fix|byte(n))
+	case code8 != 0 && n <= math.MaxUint8:
+		return append(b, code8, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, code16), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, code32), uint32(n))
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// the OpenAPI document describing the API, and the page rendering it
+var (
+	//go:embed openapi.json
+	openAPIDocument []byte
+
+	//go:embed docs.html
+	docsPage []byte
+)
+
+// get /v1/openapi.json
+func (app *application) openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+
+	w.Write(openAPIDocument)
+}
+
+// get /v1/docs
+func (app *application) docsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; script-src 'unsafe-inline'; style-src 'unsafe-inline'; connect-src 'self'")
+
+	w.Write(docsPage)
+}
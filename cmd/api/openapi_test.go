@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// httprouter wildcards, like :id, which OpenAPI writes as {id}
+var routeParamRX = regexp.MustCompile(`:([A-Za-z0-9_]+)`)
+
+// every route must be described by the OpenAPI document, with the permission
+// it requires, and the document must not describe routes that don't exist
+func TestOpenAPIDescribesRoutes(t *testing.T) {
+	var document struct {
+		Paths map[string]map[string]struct {
+			Permission string `json:"x-permission"`
+		} `json:"paths"`
+	}
+
+	err := json.Unmarshal(openAPIDocument, &document)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	described := make(map[string]bool)
+
+	for _, rt := range (&application{}).routeTable() {
+		path := routeParamRX.ReplaceAllString(rt.path, "{$1}")
+		method := strings.ToLower(rt.method)
+		described[method+" "+path] = true
+
+		operation, ok := document.Paths[path][method]
+		switch {
+		case !ok:
+			t.Errorf("%s %s is not documented", rt.method, path)
+		case operation.Permission != rt.permission:
+			t.Errorf("%s %s requires permission %q, not %q", rt.method, path, rt.permission, operation.Permission)
+		}
+	}
+
+	for path, operations := range document.Paths {
+		for method := range operations {
+			if !described[method+" "+path] {
+				t.Errorf("%s %s is documented but has no route", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
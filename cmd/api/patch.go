@@ -0,0 +1,416 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// media types for partial updates
+const (
+	mediaTypeMergePatch = "application/merge-patch+json" // RFC 7396
+	mediaTypeJSONPatch  = "application/json-patch+json"  // RFC 6902
+)
+
+// isPatchRequest reports whether the request body is a merge patch or a JSON patch
+func isPatchRequest(r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mediaType == mediaTypeMergePatch || mediaType == mediaTypeJSONPatch
+}
+
+// readPatch applies the merge patch or JSON patch in the request body to the
+// JSON representation of dst, and decodes the result back into dst. dst should
+// be a pointer to a struct holding the writable fields of a resource, filled
+// in with their current values. fields removed by the patch end up zeroed, so
+// the caller must validate dst afterwards
+func (app *application) readPatch(w http.ResponseWriter, r *http.Request, dst any) error {
+	current, err := json.Marshal(dst)
+	if err != nil {
+		return err
+	}
+
+	var doc any
+	err = json.Unmarshal(current, &doc)
+	if err != nil {
+		return err
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	switch mediaType {
+	case mediaTypeMergePatch:
+		var patch any
+
+		err = app.readJSON(w, r, &patch)
+		if err != nil {
+			return err
+		}
+
+		doc = mergePatch(doc, patch)
+
+	case mediaTypeJSONPatch:
+		var operations []map[string]json.RawMessage
+
+		err = app.readJSON(w, r, &operations)
+		if err != nil {
+			return err
+		}
+
+		doc, err = jsonPatch(doc, operations)
+		if err != nil {
+			return err
+		}
+
+	default:
+		return fmt.Errorf("unsupported patch media type %q", mediaType)
+	}
+
+	patched, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	// start from scratch, so that removed fields don't keep their old values
+	reflect.ValueOf(dst).Elem().SetZero()
+
+	dec := json.NewDecoder(bytes.NewReader(patched))
+	dec.DisallowUnknownFields()
+
+	err = dec.Decode(dst)
+	if err != nil {
+		return decodeError(err)
+	}
+
+	return nil
+}
+
+// mergePatch applies an RFC 7396 merge patch: objects are merged
+// recursively, nulls remove members and anything else replaces the target
+func mergePatch(target, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]any)
+	if !ok {
+		targetObject = map[string]any{}
+	}
+
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+		} else {
+			targetObject[key] = mergePatch(targetObject[key], value)
+		}
+	}
+
+	return targetObject
+}
+
+// jsonPatch applies the operations of an RFC 6902 JSON patch in order;
+// if any of them fails, the whole patch fails
+func jsonPatch(doc any, operations []map[string]json.RawMessage) (any, error) {
+	for i, operation := range operations {
+		var op, path, from string
+
+		err := unmarshalMember(operation, "op", &op)
+		if err == nil {
+			err = unmarshalMember(operation, "path", &path)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("patch operation %d: %w", i, err)
+		}
+
+		tokens, err := parsePointer(path)
+		if err != nil {
+			return nil, fmt.Errorf("patch operation %d: %w", i, err)
+		}
+
+		var value any
+		_, hasValue := operation["value"]
+		if hasValue {
+			err = json.Unmarshal(operation["value"], &value)
+			if err != nil {
+				return nil, fmt.Errorf("patch operation %d: invalid value", i)
+			}
+		}
+
+		switch op {
+		case "add", "replace", "test":
+			if !hasValue {
+				return nil, fmt.Errorf("patch operation %d: missing value", i)
+			}
+
+			switch op {
+			case "add":
+				doc, err = pointerAdd(doc, tokens, value)
+			case "replace":
+				if len(tokens) == 0 {
+					doc = value
+					break
+				}
+				doc, _, err = pointerRemove(doc, tokens)
+				if err == nil {
+					doc, err = pointerAdd(doc, tokens, value)
+				}
+			case "test":
+				var actual any
+				actual, err = pointerGet(doc, tokens)
+				if err == nil && !jsonEqual(actual, value) {
+					err = fmt.Errorf("test failed for path %q", path)
+				}
+			}
+
+		case "remove":
+			doc, _, err = pointerRemove(doc, tokens)
+
+		case "move", "copy":
+			err = unmarshalMember(operation, "from", &from)
+			if err != nil {
+				break
+			}
+
+			var fromTokens []string
+			fromTokens, err = parsePointer(from)
+			if err != nil {
+				break
+			}
+
+			if op == "move" {
+				if strings.HasPrefix(path, from+"/") {
+					err = errors.New("can't move a value into one of its children")
+					break
+				}
+
+				doc, value, err = pointerRemove(doc, fromTokens)
+			} else {
+				value, err = pointerGet(doc, fromTokens)
+				if err == nil {
+					value, err = deepCopy(value)
+				}
+			}
+
+			if err == nil {
+				doc, err = pointerAdd(doc, tokens, value)
+			}
+
+		default:
+			err = fmt.Errorf("unsupported op %q", op)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("patch operation %d: %w", i, err)
+		}
+	}
+
+	return doc, nil
+}
+
+// decode a required string member of a patch operation
+func unmarshalMember(operation map[string]json.RawMessage, key string, dst *string) error {
+	raw, ok := operation[key]
+	if !ok {
+		return fmt.Errorf("missing %s", key)
+	}
+
+	err := json.Unmarshal(raw, dst)
+	if err != nil {
+		return fmt.Errorf("%s must be a string", key)
+	}
+
+	return nil
+}
+
+// parsePointer splits an RFC 6901 JSON pointer into its unescaped tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(tokens[i], "~1", "/"), "~0", "~")
+	}
+
+	return tokens, nil
+}
+
+// parse an array index token; "-" (the end of the array) is allowed when adding
+func arrayIndex(token string, length int, allowEnd bool) (int, error) {
+	if token == "-" && allowEnd {
+		return length, nil
+	}
+
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || (token != "0" && strings.HasPrefix(token, "0")) {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+
+	last := length - 1
+	if allowEnd {
+		last = length
+	}
+
+	if i > last {
+		return 0, fmt.Errorf("array index %d out of bounds", i)
+	}
+
+	return i, nil
+}
+
+func pointerGet(doc any, tokens []string) (any, error) {
+	for _, token := range tokens {
+		switch node := doc.(type) {
+		case map[string]any:
+			child, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("path member %q not found", token)
+			}
+			doc = child
+
+		case []any:
+			i, err := arrayIndex(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[i]
+
+		default:
+			return nil, fmt.Errorf("path member %q not found", token)
+		}
+	}
+
+	return doc, nil
+}
+
+// pointerAdd adds value at the location, returning the updated document
+func pointerAdd(doc any, tokens []string, value any) (any, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+
+	token, rest := tokens[0], tokens[1:]
+
+	switch node := doc.(type) {
+	case map[string]any:
+		if len(rest) == 0 {
+			node[token] = value
+			return node, nil
+		}
+
+		child, ok := node[token]
+		if !ok {
+			return nil, fmt.Errorf("path member %q not found", token)
+		}
+
+		child, err := pointerAdd(child, rest, value)
+		if err != nil {
+			return nil, err
+		}
+		node[token] = child
+		return node, nil
+
+	case []any:
+		if len(rest) == 0 {
+			i, err := arrayIndex(token, len(node), true)
+			if err != nil {
+				return nil, err
+			}
+			return append(node[:i], append([]any{value}, node[i:]...)...), nil
+		}
+
+		i, err := arrayIndex(token, len(node), false)
+		if err != nil {
+			return nil, err
+		}
+
+		child, err := pointerAdd(node[i], rest, value)
+		if err != nil {
+			return nil, err
+		}
+		node[i] = child
+		return node, nil
+
+	default:
+		return nil, fmt.Errorf("path member %q not found", token)
+	}
+}
+
+// pointerRemove removes the value at the location, returning the updated
+// document and the removed value
+func pointerRemove(doc any, tokens []string) (any, any, error) {
+	if len(tokens) == 0 {
+		return nil, nil, errors.New("can't remove the whole document")
+	}
+
+	token, rest := tokens[0], tokens[1:]
+
+	switch node := doc.(type) {
+	case map[string]any:
+		child, ok := node[token]
+		if !ok {
+			return nil, nil, fmt.Errorf("path member %q not found", token)
+		}
+
+		if len(rest) == 0 {
+			delete(node, token)
+			return node, child, nil
+		}
+
+		child, removed, err := pointerRemove(child, rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		node[token] = child
+		return node, removed, nil
+
+	case []any:
+		i, err := arrayIndex(token, len(node), false)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if len(rest) == 0 {
+			removed := node[i]
+			return append(node[:i:i], node[i+1:]...), removed, nil
+		}
+
+		child, removed, err := pointerRemove(node[i], rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		node[i] = child
+		return node, removed, nil
+
+	default:
+		return nil, nil, fmt.Errorf("path member %q not found", token)
+	}
+}
+
+// compare two decoded JSON values
+func jsonEqual(a, b any) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+// copy a decoded JSON value, so that "copy" doesn't alias the source
+func deepCopy(value any) (any, error) {
+	j, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var copied any
+	err = json.Unmarshal(j, &copied)
+	return copied, err
+}
@@ -0,0 +1,553 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// responseFormat is a representation that envelopes can be rendered as
+type responseFormat struct {
+	name        string
+	contentType string
+}
+
+var (
+	formatJSON    = responseFormat{"json", "application/json"}
+	formatXML     = responseFormat{"xml", "application/xml"}
+	formatCSV     = responseFormat{"csv", "text/csv"}
+	formatMsgPack = responseFormat{"msgpack", "application/vnd.msgpack"}
+
+	// RFC 9457 problem details are JSON with a media type of their own
+	formatProblemJSON = responseFormat{"json", "application/problem+json"}
+)
+
+// media types accepted in the Accept header, and the formats they select
+var acceptFormats = map[string]responseFormat{
+	"application/json":        formatJSON,
+	"application/*":           formatJSON,
+	"*/*":                     formatJSON,
+	"application/xml":         formatXML,
+	"text/xml":                formatXML,
+	"text/csv":                formatCSV,
+	"application/vnd.msgpack": formatMsgPack,
+	"application/msgpack":     formatMsgPack,
+	"application/x-msgpack":   formatMsgPack,
+
+	// clients asking for problem details get them for errors, and JSON otherwise
+	"application/problem+json": formatJSON,
+}
+
+// errNotRenderable is returned for envelopes that have no representation in a format
+var errNotRenderable = errors.New("envelope can't be rendered in the requested format")
+
+// negotiateFormat picks the response format from the Accept header, preferring
+// higher q-values and, between equal ones, specific types over wildcards. no
+// Accept header means JSON; false means none of the accepted types is supported
+func negotiateFormat(r *http.Request) (responseFormat, bool) {
+	accept := strings.Join(r.Header.Values("Accept"), ",")
+	if strings.TrimSpace(accept) == "" {
+		return formatJSON, true
+	}
+
+	var (
+		best         responseFormat
+		bestQ        float64
+		bestSpecific bool
+		found        bool
+	)
+
+	for _, accepted := range acceptedMediaTypes(r) {
+		format, ok := acceptFormats[accepted.mediaType]
+		if !ok {
+			continue
+		}
+
+		q := accepted.q
+		specific := !strings.Contains(accepted.mediaType, "*")
+
+		if !found || q > bestQ || (q == bestQ && specific && !bestSpecific) {
+			best, bestQ, bestSpecific, found = format, q, specific, true
+		}
+	}
+
+	return best, found
+}
+
+// acceptedMediaType is a media type from the Accept header, with its q-value
+type acceptedMediaType struct {
+	mediaType string
+	q         float64
+}
+
+// acceptedMediaTypes returns the media types in the Accept header in order,
+// leaving out malformed ones and the ones refused with q=0
+func acceptedMediaTypes(r *http.Request) []acceptedMediaType {
+	var accepted []acceptedMediaType
+
+	for _, part := range strings.Split(strings.Join(r.Header.Values("Accept"), ","), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if s, ok := params["q"]; ok {
+			q, err = strconv.ParseFloat(s, 64)
+			if err != nil || q <= 0 {
+				continue
+			}
+		}
+
+		accepted = append(accepted, acceptedMediaType{mediaType, q})
+	}
+
+	return accepted
+}
+
+// readPretty reports whether the client asked for indented output with ?pretty
+func readPretty(r *http.Request) bool {
+	qs := r.URL.Query()
+	if !qs.Has("pretty") {
+		return false
+	}
+
+	pretty, err := strconv.ParseBool(qs.Get("pretty"))
+	return err != nil || pretty
+}
+
+// render writes an envelope in the format negotiated from the Accept header:
+// JSON (compact, or indented with ?pretty), XML, MessagePack, or CSV for
+// envelopes holding a list. clients that accept none of them get a
+// 406 Not Acceptable instead
+func (app *application) render(w http.ResponseWriter, r *http.Request, status int, env envelope, headers http.Header) error {
+	format, ok := negotiateFormat(r)
+	if !ok {
+		app.notAcceptableResponse(w, r)
+		return nil
+	}
+
+	body, err := encodeEnvelope(env, format, readPretty(r), false)
+	if errors.Is(err, errNotRenderable) {
+		app.notAcceptableResponse(w, r)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	writeBody(w, status, format, body, headers)
+
+	return nil
+}
+
+// renderError is like render, but falls back to JSON rather than failing,
+// so that every error reaches the client in some form. problem details
+// are sent as application/problem+json instead of plain JSON
+func (app *application) renderError(w http.ResponseWriter, r *http.Request, status int, env envelope, problem bool) error {
+	format, ok := negotiateFormat(r)
+	if !ok {
+		format = formatJSON
+	}
+
+	if problem && format == formatJSON {
+		format = formatProblemJSON
+	}
+
+	body, err := encodeEnvelope(env, format, readPretty(r), true)
+	if err != nil {
+		return err
+	}
+
+	writeBody(w, status, format, body, nil)
+
+	return nil
+}
+
+func writeBody(w http.ResponseWriter, status int, format responseFormat, body []byte, headers http.Header) {
+	// write headers
+	for key, value := range headers {
+		w.Header()[key] = value
+	}
+
+	w.Header().Set("Content-Type", format.contentType)
+	w.Header().Add("Vary", "Accept")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// encodeEnvelope encodes env in the format. CSV only fits envelopes with a single
+// list, unless flat is set, in which case anything else is written as key/value rows
+func encodeEnvelope(env envelope, format responseFormat, pretty, flat bool) ([]byte, error) {
+	if format.name == formatJSON.name {
+		j, err := json.Marshal(env)
+		if err != nil {
+			return nil, err
+		}
+
+		if pretty {
+			var buf bytes.Buffer
+			json.Indent(&buf, j, "", "\t")
+			j = buf.Bytes()
+		}
+
+		// terminal output niceness
+		return append(j, '\n'), nil
+	}
+
+	// the other formats walk the JSON representation, so that they follow
+	// the same field names and omissions
+	doc, err := toDocument(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case formatXML:
+		return encodeXML(doc, pretty)
+	case formatCSV:
+		return encodeCSV(doc, flat)
+	case formatMsgPack:
+		return appendMsgPack(nil, doc)
+	default:
+		return nil, fmt.Errorf("unknown response format %q", format.name)
+	}
+}
+
+// member is a key and value of a JSON object
+type member struct {
+	Key   string
+	Value any
+}
+
+// object is a decoded JSON object that keeps the order of its members
+type object []member
+
+func (o object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+
+	buf.WriteByte('{')
+	for i, m := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		key, err := json.Marshal(m.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := json.Marshal(m.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// toDocument returns the JSON representation of a value as nil, bool,
+// json.Number, string, []any and object values
+func toDocument(value any) (any, error) {
+	j, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(j))
+	dec.UseNumber()
+
+	return decodeValue(dec)
+}
+
+func decodeValue(dec *json.Decoder) (any, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		o := object{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+
+			value, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+
+			o = append(o, member{Key: key.(string), Value: value})
+		}
+		_, err = dec.Token()
+		return o, err
+
+	case json.Delim('['):
+		a := []any{}
+		for dec.More() {
+			value, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, value)
+		}
+		_, err = dec.Token()
+		return a, err
+
+	default:
+		return token, nil
+	}
+}
+
+// encodeCSV writes the single list in a document as rows, one column per
+// field, in the order the fields first appear. the rest of the envelope,
+// like pagination metadata, has no place in CSV and is left out
+func encodeCSV(doc any, flat bool) ([]byte, error) {
+	var rows []any
+	lists := 0
+
+	if o, ok := doc.(object); ok {
+		for _, m := range o {
+			if a, ok := m.Value.([]any); ok {
+				rows = a
+				lists++
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	cw := csv.NewWriter(&buf)
+
+	switch {
+	case lists == 1:
+		var columns []string
+		seen := map[string]bool{}
+
+		for _, row := range rows {
+			o, ok := row.(object)
+			if !ok {
+				return nil, errNotRenderable
+			}
+
+			for _, m := range o {
+				if !seen[m.Key] {
+					seen[m.Key] = true
+					columns = append(columns, m.Key)
+				}
+			}
+		}
+
+		cw.Write(columns)
+
+		for _, row := range rows {
+			values := make(map[string]any)
+			for _, m := range row.(object) {
+				values[m.Key] = m.Value
+			}
+
+			record := make([]string, len(columns))
+			for i, column := range columns {
+				cell, err := csvCell(values[column])
+				if err != nil {
+					return nil, err
+				}
+				record[i] = cell
+			}
+
+			cw.Write(record)
+		}
+
+	case flat:
+		cw.Write([]string{"key", "value"})
+
+		err := flatten("", doc, func(key, value string) {
+			cw.Write([]string{key, value})
+		})
+		if err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, errNotRenderable
+	}
+
+	cw.Flush()
+
+	return buf.Bytes(), cw.Error()
+}
+
+// csvCell formats a value for a CSV cell; lists of scalars are separated by "|",
+// like the genres of the movie import, and anything nested is written as JSON
+func csvCell(value any) (string, error) {
+	switch value := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return value, nil
+	case json.Number:
+		return value.String(), nil
+	case bool:
+		return strconv.FormatBool(value), nil
+	case []any:
+		cells := make([]string, len(value))
+		for i, v := range value {
+			switch v.(type) {
+			case object, []any:
+				j, err := json.Marshal(value)
+				return string(j), err
+			}
+
+			cells[i], _ = csvCell(v)
+		}
+		return strings.Join(cells, "|"), nil
+	default:
+		j, err := json.Marshal(value)
+		return string(j), err
+	}
+}
+
+// flatten calls fn for every scalar in a document, with dotted keys like "error.title"
+func flatten(prefix string, value any, fn func(key, value string)) error {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+
+	switch value := value.(type) {
+	case object:
+		for _, m := range value {
+			err := flatten(join(m.Key), m.Value, fn)
+			if err != nil {
+				return err
+			}
+		}
+	case []any:
+		for i, v := range value {
+			err := flatten(join(strconv.Itoa(i)), v, fn)
+			if err != nil {
+				return err
+			}
+		}
+	default:
+		cell, err := csvCell(value)
+		if err != nil {
+			return err
+		}
+		fn(prefix, cell)
+	}
+
+	return nil
+}
+
+// encodeXML writes a document inside a <response> element. object members become
+// elements named after their keys, and list elements become <item> elements
+func encodeXML(doc any, pretty bool) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+
+	enc := xml.NewEncoder(&buf)
+	if pretty {
+		enc.Indent("", "\t")
+	}
+
+	err := encodeXMLElement(enc, xml.StartElement{Name: xml.Name{Local: "response"}}, doc)
+	if err != nil {
+		return nil, err
+	}
+
+	err = enc.Flush()
+	if err != nil {
+		return nil, err
+	}
+
+	buf.WriteByte('\n')
+
+	return buf.Bytes(), nil
+}
+
+func encodeXMLElement(enc *xml.Encoder, start xml.StartElement, value any) error {
+	err := enc.EncodeToken(start)
+	if err != nil {
+		return err
+	}
+
+	switch value := value.(type) {
+	case object:
+		for _, m := range value {
+			child := xml.StartElement{Name: xml.Name{Local: m.Key}}
+
+			// keys like validation error fields aren't always valid element names
+			if !isXMLName(m.Key) {
+				child = xml.StartElement{
+					Name: xml.Name{Local: "entry"},
+					Attr: []xml.Attr{{Name: xml.Name{Local: "key"}, Value: m.Key}},
+				}
+			}
+
+			err = encodeXMLElement(enc, child, m.Value)
+			if err != nil {
+				return err
+			}
+		}
+
+	case []any:
+		for _, v := range value {
+			err = encodeXMLElement(enc, xml.StartElement{Name: xml.Name{Local: "item"}}, v)
+			if err != nil {
+				return err
+			}
+		}
+
+	case nil:
+		// an empty element
+
+	default:
+		cell, err := csvCell(value)
+		if err != nil {
+			return err
+		}
+
+		err = enc.EncodeToken(xml.CharData(cell))
+		if err != nil {
+			return err
+		}
+	}
+
+	return enc.EncodeToken(start.End())
+}
+
+// isXMLName reports whether s can be used as an element name as is
+func isXMLName(s string) bool {
+	if s == "" || strings.HasPrefix(strings.ToLower(s), "xml") {
+		return false
+	}
+
+	for i, r := range s {
+		switch {
+		case unicode.IsLetter(r) || r == '_':
+		case i > 0 && (unicode.IsDigit(r) || r == '-' || r == '.'):
+		default:
+			return false
+		}
+	}
+
+	return true
+}
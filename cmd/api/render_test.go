@@ -0,0 +1,38 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   responseFormat
+		ok     bool
+	}{
+		{"", formatJSON, true},
+		{"*/*", formatJSON, true},
+		{"application/json", formatJSON, true},
+		{"application/problem+json", formatJSON, true},
+		{"application/problem+json, application/xml;q=0.5", formatJSON, true},
+		{"application/xml", formatXML, true},
+		{"application/*;q=0.5, application/xml", formatXML, true},
+		{"text/csv", formatCSV, true},
+		{"application/x-msgpack", formatMsgPack, true},
+		{"text/html", responseFormat{}, false},
+		{"application/json;q=0", responseFormat{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/v1/movies/1", nil)
+			r.Header.Set("Accept", tt.accept)
+
+			got, ok := negotiateFormat(r)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("got %v, %t, want %v, %t", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
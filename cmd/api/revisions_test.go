@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/ildx/greenlight/internal/data"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// the revisions of a movie in the trash are gone with it
+func TestRevisionsOfTrashedMovie(t *testing.T) {
+	app := newTestApplication(t)
+
+	movie := &data.Movie{Title: "Moana", Year: 2016, Runtime: 107, Genres: []string{"animation", "adventure"}}
+
+	err := app.models.Movies.Insert(movie, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = app.models.Movies.Delete(movie.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id := strconv.FormatInt(movie.ID, 10)
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		target  string
+		params  httprouter.Params
+	}{
+		{
+			name:    "list",
+			handler: app.listRevisionsHandler,
+			target:  "/v1/movies/" + id + "/revisions",
+			params:  httprouter.Params{{Key: "id", Value: id}},
+		},
+		{
+			name:    "show",
+			handler: app.showRevisionHandler,
+			target:  "/v1/movies/" + id + "/revisions/1",
+			params:  httprouter.Params{{Key: "id", Value: id}, {Key: "version", Value: "1"}},
+		},
+		{
+			name:    "diff",
+			handler: app.diffRevisionsHandler,
+			target:  "/v1/movies/" + id + "/diff?from=1&to=2",
+			params:  httprouter.Params{{Key: "id", Value: id}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+
+			w := app.testRequest(t, tt.handler, r, tt.params)
+			if w.Code != http.StatusNotFound {
+				t.Errorf("got status %d, want %d", w.Code, http.StatusNotFound)
+			}
+		})
+	}
+}
@@ -3,10 +3,58 @@ package main
 import (
 	"expvar"
 	"net/http"
+	"strings"
 
 	"github.com/julienschmidt/httprouter"
 )
 
+// route is an endpoint of the API
+type route struct {
+	method     string
+	path       string
+	permission string // required permission, if any
+	handler    http.HandlerFunc
+}
+
+// routeTable lists every endpoint of the API. the OpenAPI document must
+// describe all of them, see TestOpenAPIDescribesRoutes
+func (app *application) routeTable() []route {
+	return []route{
+		{http.MethodGet, "/v1/healthcheck", "", app.healthcheckHandler},
+		{http.MethodGet, "/v1/openapi.json", "", app.openAPIHandler},
+		{http.MethodGet, "/v1/docs", "", app.docsHandler},
+
+		{http.MethodGet, "/v1/movies", "movies:read", app.listMoviesHandler},
+		{http.MethodPost, "/v1/movies", "movies:write", app.createMovieHandler},
+		{http.MethodGet, "/v1/movies/suggest", "movies:read", app.suggestMoviesHandler},
+		{http.MethodGet, "/v1/movies/export", "movies:read", app.exportMoviesHandler},
+		{http.MethodGet, "/v1/movies/trash", "movies:moderate", app.listTrashHandler},
+		{http.MethodPost, "/v1/movies/import", "movies:write", app.importMoviesHandler},
+		{http.MethodGet, "/v1/movies/:id", "movies:read", app.showMovieHandler},
+		{http.MethodPatch, "/v1/movies/:id", "movies:write", app.updateMovieHandler},
+		{http.MethodDelete, "/v1/movies/:id", "movies:write", app.deleteMovieHandler},
+		{http.MethodPost, "/v1/movies/:id/restore", "movies:moderate", app.restoreMovieHandler},
+		{http.MethodGet, "/v1/movies/:id/revisions", "movies:read", app.listRevisionsHandler},
+		{http.MethodGet, "/v1/movies/:id/revisions/:version", "movies:read", app.showRevisionHandler},
+		{http.MethodGet, "/v1/movies/:id/diff", "movies:read", app.diffRevisionsHandler},
+		{http.MethodPost, "/v1/movies/:id/revert", "movies:write", app.revertMovieHandler},
+
+		{http.MethodGet, "/v1/genres", "movies:read", app.listGenresHandler},
+		{http.MethodPost, "/v1/genres", "genres:write", app.createGenreHandler},
+		{http.MethodGet, "/v1/genres/:id", "movies:read", app.showGenreHandler},
+		{http.MethodPatch, "/v1/genres/:id", "genres:write", app.updateGenreHandler},
+		{http.MethodDelete, "/v1/genres/:id", "genres:write", app.deleteGenreHandler},
+		{http.MethodPost, "/v1/genres/:id/merge", "genres:write", app.mergeGenreHandler},
+
+		{http.MethodPost, "/v1/users", "", app.registerUserHandler},
+		{http.MethodPut, "/v1/users/activated", "", app.activateUserHandler},
+
+		{http.MethodPost, "/v1/tokens/authentication", "", app.createAuthenticationTokenHandler},
+
+		{http.MethodGet, "/debug/vars", "", expvar.Handler().ServeHTTP},
+	}
+}
+
 func (app *application) routes() http.Handler {
 	// init httprouter
 	router := httprouter.New()
@@ -16,21 +64,93 @@ func (app *application) routes() http.Handler {
 
 	router.MethodNotAllowed = http.HandlerFunc(app.methodNotAllowedResponse)
 
-	router.HandlerFunc(http.MethodGet, "/v1/healthcheck", app.healthcheckHandler)
+	table := app.routeTable()
+
+	// static segments that share their position with a wildcard segment,
+	// by method and wildcard path
+	static := make(map[string]map[string]http.HandlerFunc)
+	handlers := make(map[string]http.HandlerFunc)
+
+	for _, rt := range table {
+		handler := rt.handler
+		if rt.permission != "" {
+			handler = app.requirePermission(rt.permission, handler)
+		}
+
+		if wildcard, segment, ok := shadowingWildcard(rt, table); ok {
+			key := rt.method + " " + wildcard
+			if static[key] == nil {
+				static[key] = make(map[string]http.HandlerFunc)
+			}
+			static[key][segment] = handler
+			continue
+		}
 
-	router.HandlerFunc(http.MethodGet, "/v1/movies", app.requirePermission("movies:read", app.listMoviesHandler))
-	router.HandlerFunc(http.MethodPost, "/v1/movies", app.requirePermission("movies:write", app.createMovieHandler))
-	router.HandlerFunc(http.MethodGet, "/v1/movies/:id", app.requirePermission("movies:read", app.showMovieHandler))
-	router.HandlerFunc(http.MethodPatch, "/v1/movies/:id", app.requirePermission("movies:write", app.updateMovieHandler))
-	router.HandlerFunc(http.MethodDelete, "/v1/movies/:id", app.requirePermission("movies:write", app.deleteMovieHandler))
+		handlers[rt.method+" "+rt.path] = handler
+	}
 
-	router.HandlerFunc(http.MethodPost, "/v1/users", app.registerUserHandler)
-	router.HandlerFunc(http.MethodPut, "/v1/users/activated", app.activateUserHandler)
+	for key, handler := range handlers {
+		if static[key] == nil {
+			method, path, _ := strings.Cut(key, " ")
+			router.HandlerFunc(method, path, handler)
+		}
+	}
 
-	router.HandlerFunc(http.MethodPost, "/v1/tokens/authentication", app.createAuthenticationTokenHandler)
+	// wildcard routes dispatch to the static segments they shadow
+	for key, segments := range static {
+		method, path, _ := strings.Cut(key, " ")
 
-	router.Handler(http.MethodGet, "/debug/vars", expvar.Handler())
+		next, ok := handlers[key]
+		if !ok {
+			// the wildcard only exists for deeper routes, like /v1/movies/:id/restore
+			next = app.methodNotAllowedResponse
+		}
+
+		router.HandlerFunc(method, path, app.staticSegments(path, segments, next))
+	}
 
 	// recovery must be first, so we can handle all panics
 	return app.metrics(app.recoverPanic(app.enableCORS(app.rateLimit(app.authenticate(router)))))
 }
+
+// shadowingWildcard reports whether the last segment of a route is a static segment
+// that shares its position with a wildcard segment of another route for the same
+// method, like /v1/movies/suggest and /v1/movies/:id/restore, returning the path
+// up to the wildcard segment and the static segment
+func shadowingWildcard(rt route, table []route) (string, string, bool) {
+	i := strings.LastIndex(rt.path, "/")
+	parent, segment := rt.path[:i+1], rt.path[i+1:]
+
+	if strings.HasPrefix(segment, ":") {
+		return "", "", false
+	}
+
+	for _, other := range table {
+		if other.method != rt.method || !strings.HasPrefix(other.path, parent+":") {
+			continue
+		}
+
+		wildcard, _, _ := strings.Cut(other.path[len(parent):], "/")
+		return parent + wildcard, segment, true
+	}
+
+	return "", "", false
+}
+
+// httprouter doesn't allow static segments next to a wildcard segment, so
+// routes like /v1/movies/suggest are dispatched on the value of the wildcard
+// at the end of path instead
+func (app *application) staticSegments(path string, routes map[string]http.HandlerFunc, next http.HandlerFunc) http.HandlerFunc {
+	name := path[strings.LastIndex(path, ":")+1:]
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := httprouter.ParamsFromContext(r.Context())
+
+		if handler, ok := routes[params.ByName(name)]; ok {
+			handler(w, r)
+			return
+		}
+
+		next(w, r)
+	}
+}
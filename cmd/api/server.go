@@ -50,6 +50,9 @@ func (app *application) serve() error {
 			shutdownError <- err
 		}
 
+		// tell periodic background tasks to stop
+		close(app.shutdown)
+
 		// log message to indicate that we wait for background routines to complete
 		app.logger.Info("completing background tasks", "addr", srv.Addr)
 
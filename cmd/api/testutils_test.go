@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/ildx/greenlight/internal/data"
+	"github.com/ildx/greenlight/internal/migrate"
+	"github.com/ildx/greenlight/migrations"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// newTestApplication returns an application backed by the database in
+// GREENLIGHT_TEST_DB_DSN, migrated to the latest version, and skips the test
+// when it isn't set. the database should be a throwaway one
+func newTestApplication(t *testing.T) *application {
+	t.Helper()
+
+	dsn := os.Getenv("GREENLIGHT_TEST_DB_DSN")
+	if dsn == "" {
+		t.Skip("GREENLIGHT_TEST_DB_DSN is not set")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := migrate.New(db, migrations.FS, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = migrator.Up(context.Background(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &application{
+		logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+		models:   data.NewModels(db),
+		shutdown: make(chan struct{}),
+	}
+}
+
+// testRequest calls a handler directly, as the router would after the
+// middleware authenticated an anonymous user, and returns the recorded response
+func (app *application) testRequest(t *testing.T, handler http.HandlerFunc, r *http.Request, params httprouter.Params) *httptest.ResponseRecorder {
+	t.Helper()
+
+	ctx := context.WithValue(r.Context(), httprouter.ParamsKey, params)
+	r = app.contextSetUser(r.WithContext(ctx), data.AnonymousUser)
+
+	w := httptest.NewRecorder()
+	handler(w, r)
+
+	return w
+}
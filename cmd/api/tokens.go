@@ -2,6 +2,7 @@ package main
 
 import (
 	"errors"
+	"expvar"
 	"net/http"
 	"time"
 
@@ -27,7 +28,7 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 	data.ValidatePasswordPlaintext(v, input.Password)
 
 	if !v.Valid() {
-		app.failedValidationResponse(w, r, v.Errors)
+		app.failedValidationResponse(w, r, v)
 		return
 	}
 
@@ -64,8 +65,49 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 	}
 
 	// encode token to JSON and send it to client
-	err = app.writeJSON(w, http.StatusCreated, envelope{"authentication_token": token}, nil)
+	err = app.render(w, r, http.StatusCreated, envelope{"authentication_token": token}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+var (
+	totalExpiredTokensDeleted      = expvar.NewInt("total_expired_tokens_deleted")
+	totalExpiredTokenCleanupErrors = expvar.NewInt("total_expired_token_cleanup_errors")
+)
+
+// deleteExpiredTokens deletes the expired tokens a batch at a time, so that
+// the table isn't locked for long. it stops between batches when the server
+// shuts down, and the next run picks up where it left off
+func (app *application) deleteExpiredTokens() {
+	batchSize := app.config.tokens.cleanupBatchSize
+
+	var total int64
+	defer func() {
+		if total > 0 {
+			app.logger.Info("deleted expired tokens", "count", total)
+		}
+	}()
+
+	for {
+		deleted, err := app.models.Tokens.DeleteExpired(batchSize)
+		if err != nil {
+			totalExpiredTokenCleanupErrors.Add(1)
+			app.logger.Error(err.Error())
+			return
+		}
+
+		total += deleted
+		totalExpiredTokensDeleted.Add(deleted)
+
+		if deleted < int64(batchSize) {
+			return
+		}
+
+		select {
+		case <-app.shutdown:
+			return
+		default:
+		}
+	}
+}
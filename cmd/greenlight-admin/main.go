@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ildx/greenlight/internal/data"
+
+	_ "github.com/lib/pq"
+)
+
+const usage = `usage: greenlight-admin [flags] <command> [args]
+
+commands:
+  users list                          list users
+  users show <email>                  show a user and their permissions
+  users create                        create a user, like the first admin
+  users activate <email>              activate a user
+  users deactivate <email>            deactivate a user and revoke their tokens
+  users set-password <email>          set the password of a user and revoke their tokens
+  permissions list                    list the permissions
+  permissions grant <email> <code>... grant permissions to a user
+  permissions revoke <email> <code>...
+                                      revoke permissions from a user
+  tokens prune                        delete expired tokens
+  tokens revoke <email>               delete the tokens of a user
+  movies purge                        permanently delete movies from the trash
+  movies restore <id>                 restore a movie from the trash
+
+run greenlight-admin <command> -h for the flags of a command
+
+flags:
+`
+
+// admin holds the global settings of a command
+type admin struct {
+	models data.Models
+	dryRun bool
+	yes    bool
+	stdin  *bufio.Reader
+}
+
+var (
+	// errUsage is returned for invalid arguments, after the usage is printed
+	errUsage = errors.New("invalid arguments")
+
+	// errAborted is returned when a destructive action isn't confirmed
+	errAborted = errors.New("aborted")
+)
+
+// operate a deployment straight from the database, for the tasks that
+// the API doesn't cover:
+//
+//	go run ./cmd/greenlight-admin users create -name=Admin -email=admin@example.com -admin
+//	go run ./cmd/greenlight-admin -dry-run tokens prune
+func main() {
+	var (
+		dsn    string
+		dryRun bool
+		yes    bool
+	)
+
+	flag.StringVar(&dsn, "db-dsn", os.Getenv("GREENLIGHT_DB_DSN"), "PostgreSQL DSN")
+	flag.BoolVar(&dryRun, "dry-run", false, "Show what would change without changing anything")
+	flag.BoolVar(&yes, "yes", false, "Don't ask for confirmation of destructive actions")
+
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = db.PingContext(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	app := &admin{
+		models: data.NewModels(db),
+		dryRun: dryRun,
+		yes:    yes,
+		stdin:  bufio.NewReader(os.Stdin),
+	}
+
+	err = app.run(flag.Args())
+	switch {
+	case err == nil:
+	case errors.Is(err, errUsage), errors.Is(err, flag.ErrHelp):
+		os.Exit(2)
+	default:
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// run dispatches to the command named by the first arguments
+func (app *admin) run(args []string) error {
+	commands := map[string]func([]string) error{
+		"users list":         app.listUsers,
+		"users show":         app.showUser,
+		"users create":       app.createUser,
+		"users activate":     app.activateUser,
+		"users deactivate":   app.deactivateUser,
+		"users set-password": app.setPassword,
+		"permissions list":   app.listPermissions,
+		"permissions grant":  app.grantPermissions,
+		"permissions revoke": app.revokePermissions,
+		"tokens prune":       app.pruneTokens,
+		"tokens revoke":      app.revokeTokens,
+		"movies purge":       app.purgeMovies,
+		"movies restore":     app.restoreMovie,
+	}
+
+	if len(args) > 1 {
+		if command, ok := commands[args[0]+" "+args[1]]; ok {
+			return command(args[2:])
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", strings.Join(args[:min(len(args), 2)], " "))
+	flag.Usage()
+	return errUsage
+}
+
+// flagSet returns the flags of a command, which print their usage on errors
+func flagSet(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: greenlight-admin %s [flags] %s\n\nflags:\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseArgs parses the flags of a command and checks that at least min
+// positional arguments follow them, or exactly min if exact is set
+func parseArgs(fs *flag.FlagSet, args []string, min int, exact bool) ([]string, error) {
+	err := fs.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if fs.NArg() < min || (exact && fs.NArg() != min) {
+		fs.Usage()
+		return nil, errUsage
+	}
+
+	return fs.Args(), nil
+}
+
+// apply runs a change, unless this is a dry run. destructive changes must
+// be confirmed first, unless -yes is set
+func (app *admin) apply(description string, destructive bool, change func() error) error {
+	if app.dryRun {
+		fmt.Fprintln(os.Stderr, "dry run: would", description)
+		return nil
+	}
+
+	if destructive && !app.yes {
+		fmt.Fprintf(os.Stderr, "%s? [y/N] ", capitalize(description))
+
+		answer, err := app.stdin.ReadString('\n')
+		if err != nil && answer == "" {
+			fmt.Fprintln(os.Stderr)
+			return errAborted
+		}
+
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		if answer != "y" && answer != "yes" {
+			return errAborted
+		}
+	}
+
+	return change()
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// userByEmail looks up the user a command is about
+func (app *admin) userByEmail(email string) (*data.User, error) {
+	user, err := app.models.Users.GetByEmail(email)
+	if errors.Is(err, data.ErrRecordNotFound) {
+		return nil, fmt.Errorf("no user with email %s", email)
+	}
+	return user, err
+}
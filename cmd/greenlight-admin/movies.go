@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/ildx/greenlight/internal/data"
+)
+
+// movies purge [-retention=<duration>]
+func (app *admin) purgeMovies(args []string) error {
+	fs := flagSet("movies purge", "")
+	retention := fs.Duration("retention", 30*24*time.Hour, "Only purge movies that have been in the trash for longer than this")
+
+	_, err := parseArgs(fs, args, 0, true)
+	if err != nil {
+		return err
+	}
+
+	purgeable, err := app.models.Movies.CountPurgeable(*retention)
+	if err != nil {
+		return err
+	}
+
+	if purgeable == 0 {
+		fmt.Fprintln(os.Stderr, "no movies to purge")
+		return nil
+	}
+
+	return app.apply(fmt.Sprintf("permanently delete %d movies from the trash", purgeable), true, func() error {
+		purged, err := app.models.Movies.Purge(*retention)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(os.Stderr, "purged %d movies\n", purged)
+		return nil
+	})
+}
+
+// movies restore [-editor-id=<id>] <id>
+func (app *admin) restoreMovie(args []string) error {
+	fs := flagSet("movies restore", "<id>")
+	editorID := fs.Int64("editor-id", 0, "User ID recorded as the editor of the restored movie")
+
+	args, err := parseArgs(fs, args, 1, true)
+	if err != nil {
+		return err
+	}
+
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil || id < 1 {
+		return fmt.Errorf("invalid id %q", args[0])
+	}
+
+	return app.apply(fmt.Sprintf("restore movie %d", id), false, func() error {
+		movie, err := app.models.Movies.Restore(id, *editorID)
+		if errors.Is(err, data.ErrRecordNotFound) {
+			return fmt.Errorf("movie %d isn't in the trash", id)
+		}
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(os.Stderr, "restored movie %d, %q\n", movie.ID, movie.Title)
+		return nil
+	})
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ildx/greenlight/internal/data"
+)
+
+// permissions list
+func (app *admin) listPermissions(args []string) error {
+	_, err := parseArgs(flagSet("permissions list", ""), args, 0, true)
+	if err != nil {
+		return err
+	}
+
+	permissions, err := app.models.Permissions.GetAll()
+	if err != nil {
+		return err
+	}
+
+	for _, code := range permissions {
+		fmt.Println(code)
+	}
+
+	return nil
+}
+
+// permissions grant <email> <code>...
+func (app *admin) grantPermissions(args []string) error {
+	args, err := parseArgs(flagSet("permissions grant", "<email> <code>..."), args, 2, false)
+	if err != nil {
+		return err
+	}
+
+	user, err := app.userByEmail(args[0])
+	if err != nil {
+		return err
+	}
+
+	codes := data.Permissions(args[1:])
+
+	err = app.checkPermissions(codes)
+	if err != nil {
+		return err
+	}
+
+	return app.apply(fmt.Sprintf("grant %s to %s", strings.Join(codes, ", "), user.Email), false, func() error {
+		return app.models.Permissions.AddForUser(user.ID, codes...)
+	})
+}
+
+// permissions revoke <email> <code>...
+func (app *admin) revokePermissions(args []string) error {
+	args, err := parseArgs(flagSet("permissions revoke", "<email> <code>..."), args, 2, false)
+	if err != nil {
+		return err
+	}
+
+	user, err := app.userByEmail(args[0])
+	if err != nil {
+		return err
+	}
+
+	codes := data.Permissions(args[1:])
+
+	err = app.checkPermissions(codes)
+	if err != nil {
+		return err
+	}
+
+	return app.apply(fmt.Sprintf("revoke %s from %s", strings.Join(codes, ", "), user.Email), true, func() error {
+		revoked, err := app.models.Permissions.RemoveForUser(user.ID, codes...)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(os.Stderr, "revoked %d permissions\n", revoked)
+		return nil
+	})
+}
+
+// checkPermissions makes sure that every code is a known permission
+func (app *admin) checkPermissions(codes data.Permissions) error {
+	known, err := app.models.Permissions.GetAll()
+	if err != nil {
+		return err
+	}
+
+	for _, code := range codes {
+		if !known.Include(code) {
+			return fmt.Errorf("unknown permission %q, expected one of %s", code, strings.Join(known, ", "))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ildx/greenlight/internal/data"
+)
+
+// tokens prune
+func (app *admin) pruneTokens(args []string) error {
+	fs := flagSet("tokens prune", "")
+	batchSize := fs.Int("batch-size", 1000, "Tokens deleted per transaction")
+
+	_, err := parseArgs(fs, args, 0, true)
+	if err != nil {
+		return err
+	}
+
+	if *batchSize < 1 {
+		fs.Usage()
+		return errUsage
+	}
+
+	expired, err := app.models.Tokens.CountExpired()
+	if err != nil {
+		return err
+	}
+
+	if expired == 0 {
+		fmt.Fprintln(os.Stderr, "no expired tokens")
+		return nil
+	}
+
+	return app.apply(fmt.Sprintf("delete %d expired tokens", expired), true, func() error {
+		var total int64
+
+		for {
+			deleted, err := app.models.Tokens.DeleteExpired(*batchSize)
+			if err != nil {
+				return err
+			}
+
+			total += deleted
+			if deleted < int64(*batchSize) {
+				break
+			}
+		}
+
+		fmt.Fprintf(os.Stderr, "deleted %d tokens\n", total)
+		return nil
+	})
+}
+
+// tokens revoke [-scope=<scope>] <email>
+func (app *admin) revokeTokens(args []string) error {
+	fs := flagSet("tokens revoke", "<email>")
+	scope := fs.String("scope", "all", "Scope of the tokens to delete (authentication|activation|all)")
+
+	args, err := parseArgs(fs, args, 1, true)
+	if err != nil {
+		return err
+	}
+
+	var scopes []string
+	switch *scope {
+	case data.ScopeAuthentication, data.ScopeActivation:
+		scopes = []string{*scope}
+	case "all":
+		scopes = []string{data.ScopeAuthentication, data.ScopeActivation}
+	default:
+		fs.Usage()
+		return errUsage
+	}
+
+	user, err := app.userByEmail(args[0])
+	if err != nil {
+		return err
+	}
+
+	return app.apply(fmt.Sprintf("delete the %s tokens of %s", *scope, user.Email), true, func() error {
+		for _, scope := range scopes {
+			err := app.models.Tokens.DeleteAllForUser(scope, user.ID)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ildx/greenlight/internal/data"
+	"github.com/ildx/greenlight/internal/validator"
+	"golang.org/x/term"
+)
+
+// users list
+func (app *admin) listUsers(args []string) error {
+	_, err := parseArgs(flagSet("users list", ""), args, 0, true)
+	if err != nil {
+		return err
+	}
+
+	users, err := app.models.Users.GetAll()
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tEMAIL\tACTIVATED\tCREATED")
+
+	for _, user := range users {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%t\t%s\n", user.ID, user.Name, user.Email, user.Activated, user.CreatedAt.Local().Format(time.DateTime))
+	}
+
+	return tw.Flush()
+}
+
+// users show <email>
+func (app *admin) showUser(args []string) error {
+	args, err := parseArgs(flagSet("users show", "<email>"), args, 1, true)
+	if err != nil {
+		return err
+	}
+
+	user, err := app.userByEmail(args[0])
+	if err != nil {
+		return err
+	}
+
+	permissions, err := app.models.Permissions.GetAllForUsers(user.ID)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "ID\t%d\n", user.ID)
+	fmt.Fprintf(tw, "Name\t%s\n", user.Name)
+	fmt.Fprintf(tw, "Email\t%s\n", user.Email)
+	fmt.Fprintf(tw, "Activated\t%t\n", user.Activated)
+	fmt.Fprintf(tw, "Created\t%s\n", user.CreatedAt.Local().Format(time.DateTime))
+	fmt.Fprintf(tw, "Permissions\t%s\n", strings.Join(permissions, ", "))
+
+	return tw.Flush()
+}
+
+// users create -name=<name> -email=<email> [-password=<password>] [-activate] [-permissions=<codes> | -admin]
+func (app *admin) createUser(args []string) error {
+	fs := flagSet("users create", "")
+	name := fs.String("name", "", "Name")
+	email := fs.String("email", "", "Email address")
+	password := fs.String("password", "", "Password; read from $GREENLIGHT_PASSWORD or stdin if empty")
+	activate := fs.Bool("activate", false, "Activate the user right away")
+	codes := fs.String("permissions", "movies:read", "Comma-separated permissions to grant")
+	isAdmin := fs.Bool("admin", false, "Activate the user and grant every permission")
+
+	_, err := parseArgs(fs, args, 0, true)
+	if err != nil {
+		return err
+	}
+
+	var permissions data.Permissions
+	if *codes != "" {
+		permissions = strings.Split(*codes, ",")
+	}
+
+	if *isAdmin {
+		*activate = true
+
+		permissions, err = app.models.Permissions.GetAll()
+		if err != nil {
+			return err
+		}
+	}
+
+	err = app.checkPermissions(permissions)
+	if err != nil {
+		return err
+	}
+
+	*password, err = app.readPassword(*password)
+	if err != nil {
+		return err
+	}
+
+	user := &data.User{
+		Name:      *name,
+		Email:     *email,
+		Activated: *activate,
+	}
+
+	err = user.Password.Set(*password)
+	if err != nil {
+		return err
+	}
+
+	v := validator.New()
+
+	if data.ValidateUser(v, user); !v.Valid() {
+		return validationError(v)
+	}
+
+	description := fmt.Sprintf("create user %s with permissions %s", user.Email, strings.Join(permissions, ", "))
+
+	return app.apply(description, false, func() error {
+		err := app.models.Users.InsertWithPermissions(user, permissions...)
+		if errors.Is(err, data.ErrDuplicateEmail) {
+			return fmt.Errorf("a user with email %s already exists", user.Email)
+		}
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(os.Stderr, "created user %d\n", user.ID)
+		return nil
+	})
+}
+
+// users activate <email>
+func (app *admin) activateUser(args []string) error {
+	args, err := parseArgs(flagSet("users activate", "<email>"), args, 1, true)
+	if err != nil {
+		return err
+	}
+
+	user, err := app.userByEmail(args[0])
+	if err != nil {
+		return err
+	}
+
+	if user.Activated {
+		fmt.Fprintln(os.Stderr, user.Email, "is already activated")
+		return nil
+	}
+
+	return app.apply("activate "+user.Email, false, func() error {
+		user.Activated = true
+
+		err := app.models.Users.Update(user)
+		if err != nil {
+			return err
+		}
+
+		// the activation tokens are of no use anymore
+		return app.models.Tokens.DeleteAllForUser(data.ScopeActivation, user.ID)
+	})
+}
+
+// users deactivate <email>
+func (app *admin) deactivateUser(args []string) error {
+	args, err := parseArgs(flagSet("users deactivate", "<email>"), args, 1, true)
+	if err != nil {
+		return err
+	}
+
+	user, err := app.userByEmail(args[0])
+	if err != nil {
+		return err
+	}
+
+	if !user.Activated {
+		fmt.Fprintln(os.Stderr, user.Email, "is already deactivated")
+		return nil
+	}
+
+	return app.apply("deactivate "+user.Email+" and revoke their authentication tokens", true, func() error {
+		user.Activated = false
+
+		err := app.models.Users.Update(user)
+		if err != nil {
+			return err
+		}
+
+		return app.models.Tokens.DeleteAllForUser(data.ScopeAuthentication, user.ID)
+	})
+}
+
+// users set-password [-password=<password>] <email>
+func (app *admin) setPassword(args []string) error {
+	fs := flagSet("users set-password", "<email>")
+	password := fs.String("password", "", "New password; read from $GREENLIGHT_PASSWORD or stdin if empty")
+
+	args, err := parseArgs(fs, args, 1, true)
+	if err != nil {
+		return err
+	}
+
+	user, err := app.userByEmail(args[0])
+	if err != nil {
+		return err
+	}
+
+	*password, err = app.readPassword(*password)
+	if err != nil {
+		return err
+	}
+
+	err = user.Password.Set(*password)
+	if err != nil {
+		return err
+	}
+
+	v := validator.New()
+
+	if data.ValidateUser(v, user); !v.Valid() {
+		return validationError(v)
+	}
+
+	return app.apply("set the password of "+user.Email+" and revoke their authentication tokens", true, func() error {
+		err := app.models.Users.Update(user)
+		if err != nil {
+			return err
+		}
+
+		return app.models.Tokens.DeleteAllForUser(data.ScopeAuthentication, user.ID)
+	})
+}
+
+// readPassword returns password, or else $GREENLIGHT_PASSWORD, or else asks for
+// it on stdin, without echoing it at a terminal
+func (app *admin) readPassword(password string) (string, error) {
+	if password != "" {
+		return password, nil
+	}
+	if password = os.Getenv("GREENLIGHT_PASSWORD"); password != "" {
+		return password, nil
+	}
+
+	fmt.Fprint(os.Stderr, "Password: ")
+
+	// don't echo passwords typed in at a terminal
+	if fd := int(os.Stdin.Fd()); term.IsTerminal(fd) {
+		b, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("reading password: %w", err)
+		}
+		return string(b), nil
+	}
+
+	line, err := app.stdin.ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("reading password: %w", err)
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// validationError lists the errors of a failed validation
+func validationError(v *validator.Validator) error {
+	var b strings.Builder
+
+	b.WriteString("invalid input:")
+	for _, field := range v.Grouped() {
+		for _, e := range field.Errors {
+			fmt.Fprintf(&b, "\n  %s: %s", field.Field, validator.Format(e.Message, e.Args...))
+		}
+	}
+
+	return errors.New(b.String())
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// greenlight login -email=<email> [-password=<password>]
+func (app *cli) login(ctx context.Context, args []string) error {
+	fs := flagSet("login", "")
+	email := fs.String("email", os.Getenv("GREENLIGHT_EMAIL"), "Email address")
+	password := fs.String("password", "", "Password; read from $GREENLIGHT_PASSWORD or stdin if empty")
+
+	_, err := parseArgs(fs, args, 0)
+	if err != nil {
+		return err
+	}
+
+	if *email == "" {
+		fs.Usage()
+		return errUsage
+	}
+
+	*password, err = readPassword(*password)
+	if err != nil {
+		return err
+	}
+
+	token, err := app.client.Tokens.CreateAuthentication(ctx, *email, *password)
+	if err != nil {
+		return err
+	}
+
+	app.config.URL = app.url
+	app.config.Token = token.Token
+	app.config.Expiry = token.Expiry
+
+	err = saveConfig(app.configPath, app.config)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "logged in to %s until %s\n", app.url, token.Expiry.Local().Format(time.DateTime))
+	return nil
+}
+
+// greenlight logout
+func (app *cli) logout(ctx context.Context, args []string) error {
+	_, err := parseArgs(flagSet("logout", ""), args, 0)
+	if err != nil {
+		return err
+	}
+
+	app.config.Token = ""
+	app.config.Expiry = time.Time{}
+
+	return saveConfig(app.configPath, app.config)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// config is what login stores between commands
+type config struct {
+	URL    string    `json:"url,omitempty"`
+	Token  string    `json:"token,omitempty"`
+	Expiry time.Time `json:"expiry,omitempty"`
+}
+
+// loadConfig reads the config file, which doesn't exist before the first login
+func loadConfig(path string) (*config, error) {
+	var cfg config
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(b, &cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return &cfg, nil
+}
+
+// saveConfig writes the config file, readable only by the user since it holds
+// the authentication token
+func saveConfig(path string, cfg *config) error {
+	err := os.MkdirAll(filepath.Dir(path), 0o700)
+	if err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(cfg, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file first, so that a failed write doesn't lose the
+	// previous config
+	tmp := path + ".tmp"
+
+	err = os.WriteFile(tmp, append(b, '\n'), 0o600)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
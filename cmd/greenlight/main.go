@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/ildx/greenlight/internal/vcs"
+	"github.com/ildx/greenlight/pkg/client"
+)
+
+// global constant holding the version of the cli
+var version = vcs.Version()
+
+const usage = `usage: greenlight [flags] <command> [args]
+
+commands:
+  login                      create an authentication token and store it
+  logout                     forget the stored authentication token
+  movies list                list movies
+  movies show <id>           show a movie
+  movies create              create a movie from a JSON body
+  movies update <id>         update a movie from a JSON body
+  movies delete <id>         delete a movie
+  users register             register a user
+  users activate <token>     activate a user
+
+run greenlight <command> -h for the flags of a command
+
+flags:
+`
+
+// cli holds the global settings of a command
+type cli struct {
+	url        string
+	client     *client.Client
+	config     *config
+	configPath string
+	output     string
+}
+
+// errUsage is returned for invalid arguments, after the usage is printed
+var errUsage = errors.New("invalid arguments")
+
+// call the API from the command line, with the token stored by login:
+//
+//	go run ./cmd/greenlight login -email=alice@example.com
+//	go run ./cmd/greenlight movies list -genres=drama
+func main() {
+	var (
+		apiURL     string
+		output     string
+		configPath string
+	)
+
+	flag.StringVar(&apiURL, "url", os.Getenv("GREENLIGHT_URL"), "API base URL; defaults to the one stored by login, or http://localhost:4000")
+	flag.StringVar(&output, "output", "table", "Output format (table|json)")
+	flag.StringVar(&configPath, "config", os.Getenv("GREENLIGHT_CONFIG"), "Config file; defaults to greenlight/config.json in the user config directory")
+
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+
+	displayVersion := flag.Bool("version", false, "Display version and exit")
+
+	flag.Parse()
+
+	if *displayVersion {
+		fmt.Printf("Version:\t%s\n", version)
+		os.Exit(0)
+	}
+
+	if output != "table" && output != "json" {
+		fmt.Fprintln(os.Stderr, "-output must be table or json")
+		os.Exit(2)
+	}
+
+	if configPath == "" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		configPath = filepath.Join(dir, "greenlight", "config.json")
+	}
+
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if apiURL == "" {
+		apiURL = cfg.URL
+	}
+	if apiURL == "" {
+		apiURL = "http://localhost:4000"
+	}
+
+	opts := []client.Option{client.WithUserAgent("greenlight-cli/" + version)}
+	if cfg.Token != "" && cfg.URL == apiURL {
+		opts = append(opts, client.WithToken(cfg.Token, cfg.Expiry))
+	}
+
+	c, err := client.New(apiURL, opts...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	app := &cli{url: apiURL, client: c, config: cfg, configPath: configPath, output: output}
+
+	err = app.run(ctx, flag.Args())
+	switch {
+	case err == nil:
+	case errors.Is(err, errUsage), errors.Is(err, flag.ErrHelp):
+		os.Exit(2)
+	case errors.Is(err, client.ErrNoCredentials), errors.Is(err, client.ErrInvalidAuthenticationToken):
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, "run greenlight login to authenticate")
+		os.Exit(1)
+	default:
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// run dispatches to the command named by the first arguments
+func (app *cli) run(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		flag.Usage()
+		return errUsage
+	}
+
+	commands := map[string]func(context.Context, []string) error{
+		"login":          app.login,
+		"logout":         app.logout,
+		"movies list":    app.listMovies,
+		"movies show":    app.showMovie,
+		"movies create":  app.createMovie,
+		"movies update":  app.updateMovie,
+		"movies delete":  app.deleteMovie,
+		"users register": app.registerUser,
+		"users activate": app.activateUser,
+	}
+
+	if command, ok := commands[args[0]]; ok {
+		return command(ctx, args[1:])
+	}
+	if len(args) > 1 {
+		if command, ok := commands[args[0]+" "+args[1]]; ok {
+			return command(ctx, args[2:])
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", strings.Join(args[:min(len(args), 2)], " "))
+	flag.Usage()
+	return errUsage
+}
+
+// flagSet returns the flags of a command, which print their usage on errors
+func flagSet(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: greenlight %s [flags] %s\n\nflags:\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseArgs parses the flags of a command and checks that the expected number
+// of positional arguments follow them
+func parseArgs(fs *flag.FlagSet, args []string, n int) ([]string, error) {
+	err := fs.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if fs.NArg() != n {
+		fs.Usage()
+		return nil, errUsage
+	}
+
+	return fs.Args(), nil
+}
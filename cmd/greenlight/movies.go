@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ildx/greenlight/pkg/client"
+)
+
+// greenlight movies list [flags]
+func (app *cli) listMovies(ctx context.Context, args []string) error {
+	var filter client.MovieFilter
+	var genres, fields string
+
+	fs := flagSet("movies list", "")
+	fs.StringVar(&filter.Title, "title", "", "Title search")
+	fs.StringVar(&filter.SearchMode, "search-mode", "", "Title search mode (plain|prefix|fuzzy)")
+	fs.StringVar(&filter.Language, "lang", "", "Language of the title search, like english")
+	fs.StringVar(&genres, "genres", "", "Comma-separated genres; prefix a genre with - to exclude it")
+	fs.StringVar(&filter.GenreMode, "genre-mode", "", "Whether movies need all or any of the genres (all|any)")
+	fs.IntVar(&filter.YearFrom, "year-from", 0, "Earliest release year")
+	fs.IntVar(&filter.YearTo, "year-to", 0, "Latest release year")
+	fs.IntVar(&filter.RuntimeMin, "runtime-min", 0, "Shortest runtime in minutes")
+	fs.IntVar(&filter.RuntimeMax, "runtime-max", 0, "Longest runtime in minutes")
+	fs.StringVar(&filter.Sort, "sort", "", "Sort order, like title or -year")
+	fs.StringVar(&fields, "fields", "", "Comma-separated fields to return")
+	fs.IntVar(&filter.Page, "page", 0, "Page number")
+	fs.IntVar(&filter.PageSize, "page-size", 0, "Movies per page")
+	all := fs.Bool("all", false, "List the movies of every page")
+
+	_, err := parseArgs(fs, args, 0)
+	if err != nil {
+		return err
+	}
+
+	if genres != "" {
+		filter.Genres = strings.Split(genres, ",")
+	}
+	if fields != "" {
+		filter.Fields = strings.Split(fields, ",")
+	}
+
+	if !*all {
+		page, err := app.client.Movies.List(ctx, filter)
+		if err != nil {
+			return err
+		}
+
+		err = app.print(page, func(w io.Writer) { movieRows(w, page.Movies, true) })
+		if err != nil {
+			return err
+		}
+
+		if meta := page.Metadata; app.output == "table" && meta.CurrentPage != 0 {
+			fmt.Fprintf(os.Stderr, "page %d of %d, %d movies\n", meta.CurrentPage, meta.LastPage, meta.TotalRecords)
+		}
+		return nil
+	}
+
+	var movies []*client.Movie
+
+	it := app.client.Movies.All(ctx, filter)
+	for it.Next() {
+		movies = append(movies, it.Movie())
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+
+	return app.print(movies, func(w io.Writer) { movieRows(w, movies, true) })
+}
+
+// greenlight movies show <id>
+func (app *cli) showMovie(ctx context.Context, args []string) error {
+	args, err := parseArgs(flagSet("movies show", "<id>"), args, 1)
+	if err != nil {
+		return err
+	}
+
+	id, err := parseID(args[0])
+	if err != nil {
+		return err
+	}
+
+	movie, err := app.client.Movies.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return app.print(movie, movieTable(movie))
+}
+
+// greenlight movies create [-file=<file>]
+func (app *cli) createMovie(ctx context.Context, args []string) error {
+	fs := flagSet("movies create", "")
+	file := fs.String("file", "-", `JSON body, like {"title": "Casablanca", "year": 1942, "runtime": "102 mins", "genres": ["drama"]}, or - for stdin`)
+
+	_, err := parseArgs(fs, args, 0)
+	if err != nil {
+		return err
+	}
+
+	var input client.MovieInput
+
+	err = readBody(*file, &input)
+	if err != nil {
+		return err
+	}
+
+	movie, err := app.client.Movies.Create(ctx, input)
+	if err != nil {
+		return err
+	}
+
+	return app.print(movie, movieTable(movie))
+}
+
+// greenlight movies update [-file=<file>] [-version=<version>] <id>
+func (app *cli) updateMovie(ctx context.Context, args []string) error {
+	fs := flagSet("movies update", "<id>")
+	file := fs.String("file", "-", `JSON body with the fields to change, like {"year": 1943}, or - for stdin`)
+	version := fs.Int("version", 0, "Only update the movie if it's still at this version")
+
+	args, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	id, err := parseID(args[0])
+	if err != nil {
+		return err
+	}
+
+	var update client.MovieUpdate
+
+	err = readBody(*file, &update)
+	if err != nil {
+		return err
+	}
+	update.Version = int32(*version)
+
+	movie, err := app.client.Movies.Update(ctx, id, update)
+	if err != nil {
+		return err
+	}
+
+	return app.print(movie, movieTable(movie))
+}
+
+// greenlight movies delete <id>
+func (app *cli) deleteMovie(ctx context.Context, args []string) error {
+	args, err := parseArgs(flagSet("movies delete", "<id>"), args, 1)
+	if err != nil {
+		return err
+	}
+
+	id, err := parseID(args[0])
+	if err != nil {
+		return err
+	}
+
+	err = app.client.Movies.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "movie %d moved to the trash\n", id)
+	return nil
+}
+
+// parseID parses a record id argument
+func parseID(s string) (int64, error) {
+	id, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || id < 1 {
+		return 0, fmt.Errorf("invalid id %q", s)
+	}
+	return id, nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ildx/greenlight/pkg/client"
+	"golang.org/x/term"
+)
+
+// print writes v as indented JSON, or as a table written by table
+func (app *cli) print(v any, table func(w io.Writer)) error {
+	if app.output == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "\t")
+		return enc.Encode(v)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	table(tw)
+	return tw.Flush()
+}
+
+// movieRows writes movies as table rows, under a header
+func movieRows(w io.Writer, movies []*client.Movie, header bool) {
+	if header {
+		fmt.Fprintln(w, "ID\tTITLE\tYEAR\tRUNTIME\tGENRES\tVERSION")
+	}
+
+	for _, m := range movies {
+		fmt.Fprintf(w, "%d\t%s\t%d\t%d mins\t%s\t%d\n", m.ID, m.Title, m.Year, m.Runtime, strings.Join(m.Genres, ", "), m.Version)
+	}
+}
+
+// movieTable writes a single movie as a table of fields
+func movieTable(movie *client.Movie) func(io.Writer) {
+	return func(w io.Writer) {
+		fmt.Fprintf(w, "ID\t%d\n", movie.ID)
+		fmt.Fprintf(w, "Title\t%s\n", movie.Title)
+		fmt.Fprintf(w, "Year\t%d\n", movie.Year)
+		fmt.Fprintf(w, "Runtime\t%d mins\n", movie.Runtime)
+		fmt.Fprintf(w, "Genres\t%s\n", strings.Join(movie.Genres, ", "))
+		fmt.Fprintf(w, "Version\t%d\n", movie.Version)
+	}
+}
+
+// userTable writes a user as a table of fields
+func userTable(user *client.User) func(io.Writer) {
+	return func(w io.Writer) {
+		fmt.Fprintf(w, "ID\t%d\n", user.ID)
+		fmt.Fprintf(w, "Name\t%s\n", user.Name)
+		fmt.Fprintf(w, "Email\t%s\n", user.Email)
+		fmt.Fprintf(w, "Activated\t%t\n", user.Activated)
+		fmt.Fprintf(w, "Created\t%s\n", user.CreatedAt.Local().Format("2006-01-02 15:04:05"))
+	}
+}
+
+// readBody decodes a JSON body from a file, or from stdin for "-". unknown
+// fields are rejected, so that typos don't go unnoticed
+func readBody(file string, dst any) error {
+	var r io.Reader = os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(dst)
+	if err != nil {
+		return fmt.Errorf("reading body from %s: %w", file, err)
+	}
+
+	return nil
+}
+
+// readPassword returns password, or else $GREENLIGHT_PASSWORD, or else asks for
+// it on stdin, without echoing it at a terminal
+func readPassword(password string) (string, error) {
+	if password != "" {
+		return password, nil
+	}
+	if password = os.Getenv("GREENLIGHT_PASSWORD"); password != "" {
+		return password, nil
+	}
+
+	fmt.Fprint(os.Stderr, "Password: ")
+
+	if fd := int(os.Stdin.Fd()); term.IsTerminal(fd) {
+		b, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("reading password: %w", err)
+		}
+		return string(b), nil
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("reading password: %w", err)
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// greenlight users register -name=<name> -email=<email> [-password=<password>]
+func (app *cli) registerUser(ctx context.Context, args []string) error {
+	fs := flagSet("users register", "")
+	name := fs.String("name", "", "Name")
+	email := fs.String("email", "", "Email address")
+	password := fs.String("password", "", "Password; read from $GREENLIGHT_PASSWORD or stdin if empty")
+
+	_, err := parseArgs(fs, args, 0)
+	if err != nil {
+		return err
+	}
+
+	*password, err = readPassword(*password)
+	if err != nil {
+		return err
+	}
+
+	user, err := app.client.Users.Register(ctx, *name, *email, *password)
+	if err != nil {
+		return err
+	}
+
+	err = app.print(user, userTable(user))
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(os.Stderr, "an activation token was sent to", user.Email)
+	return nil
+}
+
+// greenlight users activate <token>
+func (app *cli) activateUser(ctx context.Context, args []string) error {
+	args, err := parseArgs(flagSet("users activate", "<token>"), args, 1)
+	if err != nil {
+		return err
+	}
+
+	user, err := app.client.Users.Activate(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	return app.print(user, userTable(user))
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ildx/greenlight/internal/data"
+	"github.com/ildx/greenlight/internal/importer"
+
+	_ "github.com/lib/pq"
+)
+
+// import movies from a CSV or NDJSON file straight into the database,
+// bypassing the API and its rate limits:
+//
+//	go run ./cmd/import -file=movies.csv -dry-run
+func main() {
+	var (
+		dsn       string
+		file      string
+		format    string
+		dryRun    bool
+		batchSize int
+		editorID  int64
+	)
+
+	flag.StringVar(&dsn, "db-dsn", os.Getenv("GREENLIGHT_DB_DSN"), "PostgreSQL DSN")
+	flag.StringVar(&file, "file", "-", "File to import, or - for stdin")
+	flag.StringVar(&format, "format", "", "Input format (csv|ndjson); guessed from the file extension if empty")
+	flag.BoolVar(&dryRun, "dry-run", false, "Validate the input without inserting anything")
+	flag.IntVar(&batchSize, "batch-size", 500, "Movies inserted per transaction")
+	flag.Int64Var(&editorID, "editor-id", 0, "User ID recorded as the editor of the imported movies")
+
+	flag.Parse()
+
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".csv":
+			format = importer.FormatCSV
+		case ".ndjson", ".jsonl":
+			format = importer.FormatNDJSON
+		default:
+			fmt.Fprintln(os.Stderr, "unable to guess the format, please set -format")
+			os.Exit(2)
+		}
+	}
+
+	var input io.Reader = os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		input = f
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = db.PingContext(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	report, err := importer.New(data.NewModels(db), slog.New(slog.NewTextHandler(os.Stderr, nil))).Run(input, importer.Options{
+		Format:    format,
+		DryRun:    dryRun,
+		BatchSize: batchSize,
+		EditorID:  editorID,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "\t")
+
+	err = enc.Encode(report)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	fmt.Fprintf(os.Stderr, "%d rows: %d created, %d valid, %d invalid, %d failed\n",
+		report.Total, report.Created, report.Valid, report.Invalid, report.Failed)
+
+	if report.Invalid > 0 || report.Failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -9,10 +9,12 @@ require (
 	github.com/lib/pq v1.10.9
 	github.com/tomasen/realip v0.0.0-20180522021738-f0c99a92ddce
 	golang.org/x/crypto v0.21.0
+	golang.org/x/term v0.18.0
 	golang.org/x/time v0.5.0
 )
 
 require (
+	golang.org/x/sys v0.18.0 // indirect
 	gopkg.in/alexcesaro/quotedprintable.v3 v3.0.0-20150716171945-2caba252f4dc // indirect
 	gopkg.in/mail.v2 v2.3.1 // indirect
 )
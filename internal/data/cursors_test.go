@@ -0,0 +1,108 @@
+package data
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ildx/greenlight/internal/validator"
+)
+
+var testCursorKey = []byte("test cursor key")
+
+func TestCursorRoundTrip(t *testing.T) {
+	tests := []Cursor{
+		{Sort: "title", Value: "Moana", ID: 1},
+		{Sort: "-year", Value: "2016", ID: 42, Backward: true},
+		{Sort: "id", Value: "", ID: 7},
+		{Sort: "title", Value: "Amélie / 天気の子 . \"quoted\"", ID: 9},
+	}
+
+	for _, want := range tests {
+		t.Run(want.Sort+" "+want.Value, func(t *testing.T) {
+			got, err := DecodeCursor(want.Encode(testCursorKey), testCursorKey)
+			if err != nil {
+				t.Fatalf("got error %q", err)
+			}
+
+			if *got != want {
+				t.Errorf("got %+v, want %+v", *got, want)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorRejectsTampering(t *testing.T) {
+	encoded := Cursor{Sort: "title", Value: "Moana", ID: 1}.Encode(testCursorKey)
+	payload, signature, _ := strings.Cut(encoded, ".")
+
+	// a payload that moves the position, with the original signature
+	forged, _ := json.Marshal(Cursor{Sort: "title", Value: "Moana", ID: 2})
+	forgedPayload := base64.RawURLEncoding.EncodeToString(forged)
+
+	// flip a bit of the signature
+	rawSignature, _ := base64.RawURLEncoding.DecodeString(signature)
+	rawSignature[0] ^= 1
+	flippedSignature := base64.RawURLEncoding.EncodeToString(rawSignature)
+
+	tests := []struct {
+		name    string
+		encoded string
+		key     []byte
+	}{
+		{"forged payload", forgedPayload + "." + signature, testCursorKey},
+		{"tampered signature", payload + "." + flippedSignature, testCursorKey},
+		{"missing signature", payload, testCursorKey},
+		{"empty signature", payload + ".", testCursorKey},
+		{"invalid base64", payload + ".!!!", testCursorKey},
+		{"signed with another key", encoded, []byte("another key")},
+		{"empty", "", testCursorKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor, err := DecodeCursor(tt.encoded, tt.key)
+			if !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("got %+v, %v, want %v", cursor, err, ErrInvalidCursor)
+			}
+		})
+	}
+}
+
+// a cursor only makes sense for the sort order that issued it
+func TestValidateFiltersCursorSort(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor string
+		sort   string
+		code   string // of the cursor error, if any
+	}{
+		{"first page", "", "-year", ""},
+		{"same sort", Cursor{Sort: "-year", Value: "2016", ID: 1}.Encode(testCursorKey), "-year", ""},
+		{"different sort", Cursor{Sort: "title", Value: "Moana", ID: 1}.Encode(testCursorKey), "-year", validator.CodeConflict},
+		{"different direction", Cursor{Sort: "year", Value: "2016", ID: 1}.Encode(testCursorKey), "-year", validator.CodeConflict},
+		{"tampered", "tampered.cursor", "-year", validator.CodeInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+
+			ValidateFilters(v, Filters{
+				Page:         1,
+				PageSize:     20,
+				Sort:         tt.sort,
+				SortSafeList: []string{"title", "year", "-year"},
+				UseCursor:    true,
+				Cursor:       tt.cursor,
+				CursorKey:    testCursorKey,
+			})
+
+			if got := v.Codes["cursor"]; got != tt.code {
+				t.Errorf("got cursor error code %q, want %q", got, tt.code)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// rows fetched from the export cursor per round trip
+const exportBatchSize = 500
+
+// Export calls fn for every movie matching the filter, in id order. the rows
+// are read through a server-side cursor in batches, so the catalogue is never
+// held in memory as a whole. fn is called with each batch, and an error from it
+// stops the export. ctx bounds the whole export rather than a single query
+func (m MovieModel) Export(ctx context.Context, filter MovieFilter, fn func(movies []*Movie) error) error {
+	// cursors only live as long as their transaction
+	tx, err := m.DB.BeginTx(ctx, &sql.TxOptions{ReadOnly: true, Isolation: sql.LevelRepeatableRead})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := fmt.Sprintf(`
+    DECLARE movie_export NO SCROLL CURSOR FOR
+    SELECT %s
+    FROM movies
+    WHERE %s
+    ORDER BY id ASC`, movieColumnList(nil), movieFilterClause)
+
+	_, err = tx.ExecContext(ctx, query, filter.args()...)
+	if err != nil {
+		return err
+	}
+
+	fetch := fmt.Sprintf(`FETCH FORWARD %d FROM movie_export`, exportBatchSize)
+
+	for {
+		movies, err := fetchMovies(ctx, tx, fetch)
+		if err != nil {
+			return err
+		}
+
+		if len(movies) == 0 {
+			break
+		}
+
+		err = fn(movies)
+		if err != nil {
+			return err
+		}
+
+		if len(movies) < exportBatchSize {
+			break
+		}
+	}
+
+	return tx.Commit()
+}
+
+// fetchMovies runs a FETCH against the export cursor
+func fetchMovies(ctx context.Context, tx *sql.Tx, query string) ([]*Movie, error) {
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	movies := []*Movie{}
+
+	for rows.Next() {
+		var movie Movie
+
+		_, dest := movieColumns(&movie, nil)
+
+		err := rows.Scan(dest...)
+		if err != nil {
+			return nil, err
+		}
+
+		movies = append(movies, &movie)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return movies, nil
+}
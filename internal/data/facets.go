@@ -0,0 +1,120 @@
+package data
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/ildx/greenlight/internal/validator"
+)
+
+// facet names accepted by the "facets" query parameter
+const (
+	FacetGenre   = "genre"
+	FacetDecade  = "decade"
+	FacetRuntime = "runtime"
+)
+
+var FacetSafeList = []string{FacetGenre, FacetDecade, FacetRuntime}
+
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+type Facets struct {
+	Genres   []FacetCount `json:"genres,omitempty"`
+	Decades  []FacetCount `json:"decades,omitempty"`
+	Runtimes []FacetCount `json:"runtimes,omitempty"`
+}
+
+// the sql for each facet, selecting (facet, value, sort key, count) from the matches
+var facetQueries = map[string]string{
+	FacetGenre: `
+      SELECT 'genre', genre, 0, count(*)
+      FROM matches
+      CROSS JOIN unnest(matches.genres) AS genre
+      GROUP BY genre`,
+	FacetDecade: `
+      SELECT 'decade', (year / 10 * 10)::text || 's', year / 10 * 10, count(*)
+      FROM matches
+      GROUP BY year / 10 * 10`,
+	FacetRuntime: `
+      SELECT 'runtime', bucket, min(runtime), count(*)
+      FROM (
+        SELECT runtime, CASE
+            WHEN runtime < 90 THEN '<90'
+            WHEN runtime < 120 THEN '90-119'
+            WHEN runtime < 150 THEN '120-149'
+            ELSE '150+'
+          END AS bucket
+        FROM matches
+      ) AS buckets
+      GROUP BY bucket`,
+}
+
+// Facets counts the movies matching the filter per genre,
+// per decade and per runtime bucket, all in one round trip
+func (m MovieModel) Facets(filter MovieFilter, facets []string) (Facets, error) {
+	var result Facets
+
+	if len(facets) == 0 {
+		return result, nil
+	}
+
+	parts := make([]string, 0, len(facets))
+	for _, facet := range facets {
+		parts = append(parts, facetQueries[facet])
+	}
+
+	query := `
+    WITH matches AS (
+      SELECT year, runtime, genres
+      FROM movies
+      WHERE ` + movieFilterClause + `
+    )
+    SELECT facet, value, count FROM (` + strings.Join(parts, "\n      UNION ALL") + `
+    ) AS facets (facet, value, sort_key, count)
+    ORDER BY facet, sort_key ASC, count DESC, value ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, filter.args()...)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var facet string
+		var count FacetCount
+
+		err := rows.Scan(&facet, &count.Value, &count.Count)
+		if err != nil {
+			return result, err
+		}
+
+		switch facet {
+		case FacetGenre:
+			result.Genres = append(result.Genres, count)
+		case FacetDecade:
+			result.Decades = append(result.Decades, count)
+		case FacetRuntime:
+			result.Runtimes = append(result.Runtimes, count)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func ValidateFacets(v *validator.Validator, facets []string) {
+	for _, facet := range facets {
+		v.Check(validator.PermittedValue(facet, FacetSafeList...), "facets", validator.CodeNotPermitted, "invalid facet value")
+	}
+	v.Check(validator.Unique(facets), "facets", validator.CodeDuplicate, "must not contain duplicate values")
+}
@@ -11,14 +11,23 @@ type Filters struct {
 	PageSize     int
 	Sort         string
 	SortSafeList []string
+	Fields       []string
+
+	// keyset pagination; Cursor is empty for the first page
+	UseCursor    bool
+	Cursor       string
+	CursorKey    []byte
+	IncludeTotal bool
 }
 
 type Metadata struct {
-	CurrentPage  int `json:"current_page,omitempty"`
-	PageSize     int `json:"page_size,omitempty"`
-	FirstPage    int `json:"first_page,omitempty"`
-	LastPage     int `json:"last_page,omitempty"`
-	TotalRecords int `json:"total_records,omitempty"`
+	CurrentPage  int    `json:"current_page,omitempty"`
+	PageSize     int    `json:"page_size,omitempty"`
+	FirstPage    int    `json:"first_page,omitempty"`
+	LastPage     int    `json:"last_page,omitempty"`
+	TotalRecords int    `json:"total_records,omitempty"`
+	NextCursor   string `json:"next_cursor,omitempty"`
+	PrevCursor   string `json:"prev_cursor,omitempty"`
 }
 
 func (f Filters) limit() int {
@@ -45,13 +54,37 @@ func (f Filters) sortDirection() string {
 	return "ASC"
 }
 
+// decode the cursor; nil means the first page
+func (f Filters) cursor() (*Cursor, error) {
+	if f.Cursor == "" {
+		return nil, nil
+	}
+	return DecodeCursor(f.Cursor, f.CursorKey)
+}
+
+func reverseDirection(direction string) string {
+	if direction == "DESC" {
+		return "ASC"
+	}
+	return "DESC"
+}
+
 func ValidateFilters(v *validator.Validator, f Filters) {
-	v.Check(f.Page > 0, "page", "must be greater than zero")
-	v.Check(f.Page <= 10_000_000, "page", "must be a maximum of 10 million")
-	v.Check(f.PageSize > 0, "page_size", "must be greater than zero")
-	v.Check(f.PageSize <= 100, "page_size", "must be a maximum of 100")
+	v.Check(f.Page > 0, "page", validator.CodeTooSmall, "must be greater than zero", 1)
+	v.Check(f.Page <= 10_000_000, "page", validator.CodeTooLarge, "must be a maximum of 10 million", 10_000_000)
+	v.Check(f.PageSize > 0, "page_size", validator.CodeTooSmall, "must be greater than zero", 1)
+	v.Check(f.PageSize <= 100, "page_size", validator.CodeTooLarge, "must be a maximum of 100", 100)
+
+	v.Check(validator.PermittedValue(f.Sort, f.SortSafeList...), "sort", validator.CodeNotPermitted, "invalid sort value")
 
-	v.Check(validator.PermittedValue(f.Sort, f.SortSafeList...), "sort", "invalid sort value")
+	if f.UseCursor {
+		cursor, err := f.cursor()
+		if err != nil {
+			v.AddError("cursor", validator.CodeInvalid, "invalid or tampered cursor")
+		} else if cursor != nil {
+			v.Check(cursor.Sort == f.Sort, "cursor", validator.CodeConflict, "was issued for a different sort order")
+		}
+	}
 }
 
 func calculateMetadata(totalRecords, page, pageSize int) Metadata {
@@ -0,0 +1,512 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ildx/greenlight/internal/validator"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// error for trying to create a genre with a slug that's already taken
+	ErrDuplicateGenre = errors.New("duplicate genre")
+
+	// error for trying to give a genre an alias of another genre
+	ErrDuplicateAlias = errors.New("duplicate alias")
+
+	// error for trying to delete a genre that movies still reference
+	ErrGenreInUse = errors.New("genre in use")
+)
+
+// anything that isn't a letter or a digit becomes a dash in a slug
+var slugRX = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+
+type Genre struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"-"`
+	Slug      string    `json:"slug"`
+	Name      string    `json:"name"`
+	Aliases   []string  `json:"aliases"`
+	Version   int32     `json:"version"`
+}
+
+type GenreModel struct {
+	DB *sql.DB
+}
+
+// Taxonomy maps every known slug and alias (lowercased) to its canonical slug
+type Taxonomy map[string]string
+
+// Slugify turns free-text genre names like "Sci-Fi" into "sci-fi". letters
+// of any script are kept, so "Comédie" becomes "comédie"
+func Slugify(s string) string {
+	return strings.Trim(slugRX.ReplaceAllString(strings.ToLower(s), "-"), "-")
+}
+
+// Canonical returns the canonical slug for a genre name, slug or alias
+func (t Taxonomy) Canonical(genre string) (string, bool) {
+	if slug, ok := t[strings.ToLower(strings.TrimSpace(genre))]; ok {
+		return slug, true
+	}
+	slug, ok := t[Slugify(genre)]
+	return slug, ok
+}
+
+// Canonicalize maps genres onto their canonical slugs;
+// unknown genres are left untouched so that validation can report them
+func (t Taxonomy) Canonicalize(genres []string) []string {
+	if genres == nil {
+		return nil
+	}
+
+	canonical := make([]string, len(genres))
+
+	for i, genre := range genres {
+		if slug, ok := t.Canonical(genre); ok {
+			canonical[i] = slug
+		} else {
+			canonical[i] = genre
+		}
+	}
+
+	return canonical
+}
+
+// Contains returns true if the value is a canonical slug
+func (t Taxonomy) Contains(slug string) bool {
+	canonical, ok := t[slug]
+	return ok && canonical == slug
+}
+
+func (m GenreModel) Insert(genre *Genre) error {
+	query := `
+    INSERT INTO genres (slug, name)
+    VALUES ($1, $2)
+    RETURNING id, created_at, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, genre.Slug, genre.Name).Scan(&genre.ID, &genre.CreatedAt, &genre.Version)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "genres_slug_key"`:
+			return ErrDuplicateGenre
+		default:
+			return err
+		}
+	}
+
+	err = setGenreAliases(ctx, tx, genre)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m GenreModel) Get(id int64) (*Genre, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+    SELECT genres.id, genres.created_at, genres.slug, genres.name, genres.version,
+      array_remove(array_agg(genre_aliases.alias::text ORDER BY genre_aliases.alias), NULL)
+    FROM genres
+    LEFT JOIN genre_aliases ON genre_aliases.genre_id = genres.id
+    WHERE genres.id = $1
+    GROUP BY genres.id`
+
+	var genre Genre
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&genre.ID,
+		&genre.CreatedAt,
+		&genre.Slug,
+		&genre.Name,
+		&genre.Version,
+		pq.Array(&genre.Aliases),
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &genre, nil
+}
+
+func (m GenreModel) GetAll() ([]*Genre, error) {
+	query := `
+    SELECT genres.id, genres.created_at, genres.slug, genres.name, genres.version,
+      array_remove(array_agg(genre_aliases.alias::text ORDER BY genre_aliases.alias), NULL)
+    FROM genres
+    LEFT JOIN genre_aliases ON genre_aliases.genre_id = genres.id
+    GROUP BY genres.id
+    ORDER BY genres.slug ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	genres := []*Genre{}
+
+	for rows.Next() {
+		var genre Genre
+
+		err := rows.Scan(
+			&genre.ID,
+			&genre.CreatedAt,
+			&genre.Slug,
+			&genre.Name,
+			&genre.Version,
+			pq.Array(&genre.Aliases),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		genres = append(genres, &genre)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return genres, nil
+}
+
+// GetBySlugs returns the genres with the given canonical slugs
+func (m GenreModel) GetBySlugs(slugs []string) ([]*Genre, error) {
+	query := `
+    SELECT genres.id, genres.created_at, genres.slug, genres.name, genres.version,
+      array_remove(array_agg(genre_aliases.alias::text ORDER BY genre_aliases.alias), NULL)
+    FROM genres
+    LEFT JOIN genre_aliases ON genre_aliases.genre_id = genres.id
+    WHERE genres.slug = ANY($1)
+    GROUP BY genres.id
+    ORDER BY genres.slug ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(slugs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	genres := []*Genre{}
+
+	for rows.Next() {
+		var genre Genre
+
+		err := rows.Scan(
+			&genre.ID,
+			&genre.CreatedAt,
+			&genre.Slug,
+			&genre.Name,
+			&genre.Version,
+			pq.Array(&genre.Aliases),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		genres = append(genres, &genre)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return genres, nil
+}
+
+// Taxonomy loads the lookup table used to canonicalize and validate movie genres
+func (m GenreModel) Taxonomy() (Taxonomy, error) {
+	query := `
+    SELECT genres.slug, genres.slug FROM genres
+    UNION ALL
+    SELECT lower(genre_aliases.alias::text), genres.slug
+    FROM genre_aliases
+    INNER JOIN genres ON genres.id = genre_aliases.genre_id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	taxonomy := make(Taxonomy)
+
+	for rows.Next() {
+		var key, slug string
+
+		err := rows.Scan(&key, &slug)
+		if err != nil {
+			return nil, err
+		}
+
+		// slugs always win over aliases
+		if _, exists := taxonomy[key]; !exists {
+			taxonomy[key] = slug
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return taxonomy, nil
+}
+
+// Update renames a genre and replaces its aliases; a changed slug is
+// rewritten in every movie that references the old one, in revisions
+// attributed to the editor
+func (m GenreModel) Update(genre *Genre, oldSlug string, editorID int64) error {
+	query := `
+    UPDATE genres
+    SET slug = $1, name = $2, version = version + 1
+    WHERE id = $3 AND version = $4
+    RETURNING version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = setEditor(ctx, tx, editorID)
+	if err != nil {
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx, query, genre.Slug, genre.Name, genre.ID, genre.Version).Scan(&genre.Version)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "genres_slug_key"`:
+			return ErrDuplicateGenre
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	if oldSlug != genre.Slug {
+		err = replaceMovieGenre(ctx, tx, oldSlug, genre.Slug)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = setGenreAliases(ctx, tx, genre)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m GenreModel) Delete(id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+    DELETE FROM genres
+    WHERE id = $1
+    AND NOT EXISTS (SELECT 1 FROM movies WHERE genres.slug = ANY(movies.genres))
+    RETURNING id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(&id)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		// nothing deleted; find out whether the genre exists at all
+		_, err = m.Get(id)
+		if err != nil {
+			return err
+		}
+		return ErrGenreInUse
+	}
+
+	return nil
+}
+
+// Merge folds the source genre into the target: the source slug and its
+// aliases become aliases of the target, movies are rewritten to use the
+// target slug in revisions attributed to the editor, and the source genre
+// is deleted
+func (m GenreModel) Merge(source, target *Genre, editorID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = setEditor(ctx, tx, editorID)
+	if err != nil {
+		return err
+	}
+
+	err = replaceMovieGenre(ctx, tx, source.Slug, target.Slug)
+	if err != nil {
+		return err
+	}
+
+	query := `
+    UPDATE genre_aliases
+    SET genre_id = $1
+    WHERE genre_id = $2`
+
+	_, err = tx.ExecContext(ctx, query, target.ID, source.ID)
+	if err != nil {
+		return err
+	}
+
+	query = `
+    INSERT INTO genre_aliases (alias, genre_id)
+    SELECT DISTINCT unnest(ARRAY[$1, $2]::citext[]), $3::bigint
+    ON CONFLICT (alias) DO UPDATE SET genre_id = EXCLUDED.genre_id`
+
+	_, err = tx.ExecContext(ctx, query, source.Slug, source.Name, target.ID)
+	if err != nil {
+		return err
+	}
+
+	query = `
+    DELETE FROM genres
+    WHERE id = $1 AND version = $2`
+
+	result, err := tx.ExecContext(ctx, query, source.ID, source.Version)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrEditConflict
+	}
+
+	query = `
+    UPDATE genres
+    SET version = version + 1
+    WHERE id = $1 AND version = $2
+    RETURNING version`
+
+	err = tx.QueryRowContext(ctx, query, target.ID, target.Version).Scan(&target.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// replace the aliases of a genre with genre.Aliases. aliases that belong to
+// another genre stay where they are, and fail with ErrDuplicateAlias
+func setGenreAliases(ctx context.Context, tx *sql.Tx, genre *Genre) error {
+	query := `
+    DELETE FROM genre_aliases
+    WHERE genre_id = $1`
+
+	_, err := tx.ExecContext(ctx, query, genre.ID)
+	if err != nil {
+		return err
+	}
+
+	query = `
+    INSERT INTO genre_aliases (alias, genre_id)
+    SELECT DISTINCT unnest($1::citext[]), $2::bigint`
+
+	_, err = tx.ExecContext(ctx, query, pq.Array(genre.Aliases), genre.ID)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "genre_aliases_pkey"`:
+			return ErrDuplicateAlias
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// swap one genre slug for another in every movie, without creating duplicates
+func replaceMovieGenre(ctx context.Context, tx *sql.Tx, from, to string) error {
+	query := `
+    UPDATE movies
+    SET genres = CASE
+        WHEN $2 = ANY(genres) THEN array_remove(genres, $1)
+        ELSE array_replace(genres, $1, $2)
+      END,
+      version = version + 1
+    WHERE $1 = ANY(genres)`
+
+	_, err := tx.ExecContext(ctx, query, from, to)
+	return err
+}
+
+func ValidateGenre(v *validator.Validator, genre *Genre) {
+	v.Check(genre.Name != "", "name", validator.CodeRequired, "must be provided")
+	v.Check(len(genre.Name) <= 100, "name", validator.CodeTooLong, "must not be more than 100 bytes long", 100)
+
+	v.Check(genre.Slug != "", "slug", validator.CodeRequired, "must be provided")
+	v.Check(genre.Slug == Slugify(genre.Slug), "slug", validator.CodeInvalid, "must only contain lowercase letters, digits and dashes")
+
+	v.Check(len(genre.Aliases) <= 50, "aliases", validator.CodeTooMany, "must not contain more than 50 aliases", 50)
+	v.Check(validator.Unique(genre.Aliases), "aliases", validator.CodeDuplicate, "must not contain duplicate values")
+
+	for _, alias := range genre.Aliases {
+		v.Check(strings.TrimSpace(alias) != "", "aliases", validator.CodeInvalid, "must not contain empty values")
+		v.Check(len(alias) <= 100, "aliases", validator.CodeTooLong, "must not contain values more than 100 bytes long", 100)
+	}
+}
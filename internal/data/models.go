@@ -15,8 +15,10 @@ var (
 
 // Models wrapper
 type Models struct {
+	Genres      GenreModel
 	Movies      MovieModel
 	Permissions PermissionModel
+	Revisions   RevisionModel
 	Tokens      TokenModel
 	Users       UserModel
 }
@@ -24,8 +26,10 @@ type Models struct {
 // returns a Models struct containing initialized model
 func NewModels(db *sql.DB) Models {
 	return Models{
+		Genres:      GenreModel{DB: db},
 		Movies:      MovieModel{DB: db},
 		Permissions: PermissionModel{DB: db},
+		Revisions:   RevisionModel{DB: db},
 		Tokens:      TokenModel{DB: db},
 		Users:       UserModel{DB: db},
 	}
@@ -0,0 +1,147 @@
+package data
+
+import (
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/ildx/greenlight/internal/validator"
+
+	"github.com/lib/pq"
+)
+
+const (
+	GenreModeAll = "all"
+	GenreModeAny = "any"
+)
+
+const (
+	// full words, as typed
+	SearchModePlain = "plain"
+	// the last word may be incomplete, for search-as-you-type
+	SearchModePrefix = "prefix"
+	// full words, falling back to trigram similarity to tolerate typos
+	SearchModeFuzzy = "fuzzy"
+)
+
+// text search configurations that titles can be searched with. each one has
+// an index of its own, see migrations 000003 and 000008
+var SearchLanguageSafeList = []string{"simple", "english", "german", "french", "spanish", "italian", "dutch", "finnish", "swedish"}
+
+// MovieFilter holds the search criteria for movie listings;
+// zero values mean "don't filter on this"
+type MovieFilter struct {
+	Title         string
+	SearchMode    string
+	Language      string
+	Genres        []string
+	GenreMode     string
+	ExcludeGenres []string
+	YearFrom      int
+	YearTo        int
+	RuntimeMin    int
+	RuntimeMax    int
+}
+
+// movieFilterClause is the WHERE clause shared by every query that lists movies,
+// which never include the ones in the trash. all criteria are bound as parameters $1 to $11 (see MovieFilter.args), and
+// each one is a no-op when its parameter holds the zero value
+const movieFilterClause = `
+    deleted_at IS NULL
+    AND ($1 = ''
+      OR to_tsvector($9::regconfig, title) @@ to_tsquery($9::regconfig, $10)
+      OR ($11 AND $1 <% title))
+    AND (genres @> $2 OR $2 = '{}')
+    AND (genres && $3 OR $3 = '{}')
+    AND NOT (genres && $4)
+    AND (year >= $5 OR $5 = 0)
+    AND (year <= $6 OR $6 = 0)
+    AND (runtime >= $7 OR $7 = 0)
+    AND (runtime <= $8 OR $8 = 0)`
+
+// movieRelevance scores a title against the search, using the same
+// parameters as movieFilterClause; fuzzy matches rank below word matches
+const movieRelevance = `
+    (ts_rank(to_tsvector($9::regconfig, title), to_tsquery($9::regconfig, $10))
+      + CASE WHEN $11 THEN word_similarity($1, title) / 10 ELSE 0 END)`
+
+// tsquery turns the title search into a to_tsquery expression. only letters
+// and digits make it through, so user input can't inject tsquery operators
+func (f MovieFilter) tsquery() string {
+	words := strings.FieldsFunc(f.Title, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	if len(words) == 0 {
+		return ""
+	}
+
+	if f.SearchMode == SearchModePrefix {
+		words[len(words)-1] += ":*"
+	}
+
+	return strings.Join(words, " & ")
+}
+
+// args returns the parameters for movieFilterClause
+func (f MovieFilter) args() []any {
+	// nil slices would be sent as NULL rather than as empty arrays
+	genres, excludeGenres := []string{}, []string{}
+	genres = append(genres, f.Genres...)
+	excludeGenres = append(excludeGenres, f.ExcludeGenres...)
+
+	language := f.Language
+	if language == "" {
+		language = "simple"
+	}
+
+	allGenres, anyGenres := genres, []string{}
+	if f.GenreMode == GenreModeAny {
+		allGenres, anyGenres = []string{}, genres
+	}
+
+	return []any{
+		f.Title,
+		pq.Array(allGenres),
+		pq.Array(anyGenres),
+		pq.Array(excludeGenres),
+		f.YearFrom,
+		f.YearTo,
+		f.RuntimeMin,
+		f.RuntimeMax,
+		language,
+		f.tsquery(),
+		f.SearchMode == SearchModeFuzzy,
+	}
+}
+
+func ValidateMovieFilter(v *validator.Validator, f MovieFilter) {
+	v.Check(validator.PermittedValue(f.SearchMode, SearchModePlain, SearchModePrefix, SearchModeFuzzy), "search_mode", validator.CodeNotPermitted, "must be one of plain, prefix or fuzzy")
+	v.Check(validator.PermittedValue(f.Language, SearchLanguageSafeList...), "lang", validator.CodeNotPermitted, "unsupported search language")
+	v.Check(len(f.Title) <= 500, "title", validator.CodeTooLong, "must not be more than 500 bytes long", 500)
+
+	v.Check(validator.PermittedValue(f.GenreMode, GenreModeAll, GenreModeAny), "genre_mode", validator.CodeNotPermitted, "must be either all or any")
+	v.Check(len(f.Genres)+len(f.ExcludeGenres) <= 20, "genres", validator.CodeTooMany, "must not contain more than 20 genres", 20)
+
+	for _, genre := range f.ExcludeGenres {
+		v.Check(!validator.PermittedValue(genre, f.Genres...), "genres", validator.CodeConflict, "must not both include and exclude the same genre")
+	}
+
+	currentYear := time.Now().Year()
+
+	if f.YearFrom != 0 {
+		v.Check(f.YearFrom >= 1888 && f.YearFrom <= currentYear, "year_from", validator.CodeOutOfRange, "must be between 1888 and the current year", 1888, currentYear)
+	}
+	if f.YearTo != 0 {
+		v.Check(f.YearTo >= 1888 && f.YearTo <= currentYear, "year_to", validator.CodeOutOfRange, "must be between 1888 and the current year", 1888, currentYear)
+	}
+	if f.YearFrom != 0 && f.YearTo != 0 {
+		v.Check(f.YearFrom <= f.YearTo, "year_from", validator.CodeConflict, "must not be after year_to")
+	}
+
+	v.Check(f.RuntimeMin >= 0, "runtime_min", validator.CodeTooSmall, "must not be negative", 0)
+	v.Check(f.RuntimeMax >= 0, "runtime_max", validator.CodeTooSmall, "must not be negative", 0)
+	if f.RuntimeMin != 0 && f.RuntimeMax != 0 {
+		v.Check(f.RuntimeMin <= f.RuntimeMax, "runtime_min", validator.CodeConflict, "must not be greater than runtime_max")
+	}
+}
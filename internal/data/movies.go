@@ -5,6 +5,10 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/ildx/greenlight/internal/validator"
@@ -13,20 +17,66 @@ import (
 )
 
 type Movie struct {
-	ID        int64     `json:"id"`
-	CreatedAt time.Time `json:"-"`
-	Title     string    `json:"title"`
-	Year      int32     `json:"year,omitempty"`
-	Runtime   Runtime   `json:"runtime,omitempty"`
-	Genres    []string  `json:"genres,omitempty"`
-	Version   int32     `json:"version"`
+	ID        int64      `json:"id"`
+	CreatedAt time.Time  `json:"-"`
+	Title     string     `json:"title"`
+	Year      int32      `json:"year,omitempty"`
+	Runtime   Runtime    `json:"runtime,omitempty"`
+	Genres    []string   `json:"genres,omitempty"`
+	Version   int32      `json:"version"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
 type MovieModel struct {
 	DB *sql.DB
 }
 
-func (m MovieModel) Insert(movie *Movie) error {
+// fields that clients can pick with ?fields=
+var MovieFieldSafeList = []string{"id", "title", "year", "runtime", "genres", "version"}
+
+// related data that clients can embed with ?include=
+var MovieIncludeSafeList = []string{"genres"}
+
+// movieColumns returns the columns to select for the given fields, and the
+// matching scan destinations in movie. the id and version are always
+// selected, and no fields means all of them
+func movieColumns(movie *Movie, fields []string) (string, []any) {
+	all := len(fields) == 0
+
+	columns := []string{"id", "version"}
+	dest := []any{&movie.ID, &movie.Version}
+
+	if all {
+		columns = append(columns, "created_at", "deleted_at")
+		dest = append(dest, &movie.CreatedAt, &movie.DeletedAt)
+	}
+
+	optional := []struct {
+		column string
+		dest   any
+	}{
+		{"title", &movie.Title},
+		{"year", &movie.Year},
+		{"runtime", &movie.Runtime},
+		{"genres", pq.Array(&movie.Genres)},
+	}
+
+	for _, o := range optional {
+		if all || slices.Contains(fields, o.column) {
+			columns = append(columns, o.column)
+			dest = append(dest, o.dest)
+		}
+	}
+
+	return strings.Join(columns, ", "), dest
+}
+
+func movieColumnList(fields []string) string {
+	columns, _ := movieColumns(&Movie{}, fields)
+	return columns
+}
+
+func (m MovieModel) Insert(movie *Movie, editorID int64) error {
 	query := `
     INSERT INTO movies (title, year, runtime, genres)
     VALUES ($1, $2, $3, $4)
@@ -37,34 +87,124 @@ func (m MovieModel) Insert(movie *Movie) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	return m.DB.QueryRowContext(ctx, query, args...).Scan(&movie.ID, &movie.CreatedAt, &movie.Version)
+	return m.asEditor(ctx, editorID, func(tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, query, args...).Scan(&movie.ID, &movie.CreatedAt, &movie.Version)
+	})
+}
+
+// InsertBatch inserts movies in a single transaction with COPY, which is far
+// faster than one INSERT per movie; the ids aren't filled in
+func (m MovieModel) InsertBatch(movies []*Movie, editorID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	return m.asEditor(ctx, editorID, func(tx *sql.Tx) error {
+		stmt, err := tx.PrepareContext(ctx, pq.CopyIn("movies", "title", "year", "runtime", "genres"))
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		for _, movie := range movies {
+			_, err = stmt.ExecContext(ctx, movie.Title, movie.Year, movie.Runtime, pq.Array(movie.Genres))
+			if err != nil {
+				return err
+			}
+		}
+
+		// flush the buffered rows
+		_, err = stmt.ExecContext(ctx)
+		return movieConstraintError(err)
+	})
+}
+
+// MovieConstraintError is returned by InsertBatch for a movie that breaks a
+// check constraint of the movies table, which ValidateMovie normally catches
+// first, as the validation error it stands for
+type MovieConstraintError struct {
+	Index   int // of the movie in the batch, or -1 if postgres didn't say
+	Field   string
+	Code    string
+	Message string
+	Args    []any // for translations of the message, see the validator codes
+	Err     error
+}
+
+func (e *MovieConstraintError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *MovieConstraintError) Unwrap() error {
+	return e.Err
+}
+
+// the check constraints of the movies table, as validation errors
+var movieConstraints = map[string]struct {
+	field, code, message string
+	args                 func() []any
+}{
+	"movies_runtime_check": {"runtime", validator.CodeTooSmall, "must be a positive integer", func() []any { return []any{1} }},
+	"movies_year_check":    {"year", validator.CodeOutOfRange, "must be between 1888 and the current year", func() []any { return []any{1888, time.Now().Year()} }},
+	"genres_length_check":  {"genres", validator.CodeOutOfRange, "must contain between 1 and 5 genres", func() []any { return []any{1, 5} }},
+}
+
+// context postgres gives errors about a row of a COPY
+var copyLineRX = regexp.MustCompile(`COPY movies, line ([0-9]+)`)
+
+// movieConstraintError turns check constraint violations into a
+// *MovieConstraintError, and returns other errors as they are
+func movieConstraintError(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code.Name() != "check_violation" {
+		return err
+	}
+
+	constraint, ok := movieConstraints[pqErr.Constraint]
+	if !ok {
+		return err
+	}
+
+	index := -1
+	if match := copyLineRX.FindStringSubmatch(pqErr.Where); match != nil {
+		line, _ := strconv.Atoi(match[1])
+		index = line - 1
+	}
+
+	return &MovieConstraintError{
+		Index:   index,
+		Field:   constraint.field,
+		Code:    constraint.code,
+		Message: constraint.message,
+		Args:    constraint.args(),
+		Err:     err,
+	}
 }
 
 func (m MovieModel) Get(id int64) (*Movie, error) {
+	return m.GetFields(id, nil)
+}
+
+// GetFields is like Get, but only loads the given fields (and the id and version);
+// no fields means all of them
+func (m MovieModel) GetFields(id int64, fields []string) (*Movie, error) {
 	// return early for unrealistic queries
 	if id < 1 {
 		return nil, ErrRecordNotFound
 	}
 
-	query := `
-    SELECT id, created_at, title, year, runtime, genres, version
-    FROM movies
-    WHERE id = $1`
-
 	var movie Movie
 
+	columns, dest := movieColumns(&movie, fields)
+
+	query := fmt.Sprintf(`
+    SELECT %s
+    FROM movies
+    WHERE id = $1 AND deleted_at IS NULL`, columns)
+
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, id).Scan(
-		&movie.ID,
-		&movie.CreatedAt,
-		&movie.Title,
-		&movie.Year,
-		&movie.Runtime,
-		pq.Array(&movie.Genres),
-		&movie.Version,
-	)
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(dest...)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -77,19 +217,29 @@ func (m MovieModel) Get(id int64) (*Movie, error) {
 	return &movie, nil
 }
 
-func (m MovieModel) GetAll(title string, genres []string, filters Filters) ([]*Movie, Metadata, error) {
+func (m MovieModel) GetAll(filter MovieFilter, filters Filters) ([]*Movie, Metadata, error) {
+	if filters.UseCursor {
+		return m.getAllByCursor(filter, filters)
+	}
+
+	orderBy := fmt.Sprintf("%s %s", filters.sortColumn(), filters.sortDirection())
+
+	// relevance isn't a column, and most relevant always comes first
+	if filters.sortColumn() == "relevance" {
+		orderBy = movieRelevance + " DESC"
+	}
+
 	query := fmt.Sprintf(`
-    SELECT count(*) OVER(), id, created_at, title, year, runtime, genres, version
+    SELECT count(*) OVER(), %s
     FROM movies
-    WHERE (to_tsvector('simple', title) @@ plainto_tsquery('simple', $1) OR $1 = '')
-    AND (genres @> $2 OR $2 = '{}')
-    ORDER BY %s %s, id ASC
-    LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
+    WHERE %s
+    ORDER BY %s, id ASC
+    LIMIT $12 OFFSET $13`, movieColumnList(filters.Fields), movieFilterClause, orderBy)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	args := []any{title, pq.Array(genres), filters.limit(), filters.offset()}
+	args := append(filter.args(), filters.limit(), filters.offset())
 
 	rows, err := m.DB.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -104,16 +254,9 @@ func (m MovieModel) GetAll(title string, genres []string, filters Filters) ([]*M
 	for rows.Next() {
 		var movie Movie
 
-		err := rows.Scan(
-			&totalRecords,
-			&movie.ID,
-			&movie.CreatedAt,
-			&movie.Title,
-			&movie.Year,
-			&movie.Runtime,
-			pq.Array(&movie.Genres),
-			&movie.Version,
-		)
+		_, dest := movieColumns(&movie, filters.Fields)
+
+		err := rows.Scan(append([]any{&totalRecords}, dest...)...)
 		if err != nil {
 			return nil, Metadata{}, err
 		}
@@ -131,11 +274,159 @@ func (m MovieModel) GetAll(title string, genres []string, filters Filters) ([]*M
 	return movies, metadata, nil
 }
 
-func (m MovieModel) Update(movie *Movie) error {
+// sql types of the sortable columns, for casting cursor values
+var movieSortColumnTypes = map[string]string{
+	"id":      "bigint",
+	"title":   "text",
+	"year":    "integer",
+	"runtime": "integer",
+}
+
+// the value of the sort column for a movie, as stored in a cursor
+func movieSortValue(movie *Movie, column string) string {
+	switch column {
+	case "title":
+		return movie.Title
+	case "year":
+		return strconv.Itoa(int(movie.Year))
+	case "runtime":
+		return strconv.Itoa(int(movie.Runtime))
+	default:
+		return strconv.FormatInt(movie.ID, 10)
+	}
+}
+
+// getAllByCursor pages through movies with keyset pagination: rather than
+// skipping rows with OFFSET, it continues from the (sort value, id) position
+// stored in the cursor, so deep pages are cheap and stay stable when movies
+// are inserted in the meantime
+func (m MovieModel) getAllByCursor(filter MovieFilter, filters Filters) ([]*Movie, Metadata, error) {
+	cursor, err := filters.cursor()
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	column := filters.sortColumn()
+	columnType, ok := movieSortColumnTypes[column]
+	if !ok {
+		return nil, Metadata{}, fmt.Errorf("unsupported cursor sort column %q", column)
+	}
+
+	backward := cursor != nil && cursor.Backward
+
+	// walking backwards flips the order, and the page is reversed afterwards
+	direction, idDirection := filters.sortDirection(), "ASC"
+	if backward {
+		direction, idDirection = reverseDirection(direction), "DESC"
+	}
+
+	where := movieFilterClause
+	args := filter.args()
+
+	if cursor != nil {
+		comparison, idComparison := ">", ">"
+		if direction == "DESC" {
+			comparison = "<"
+		}
+		if idDirection == "DESC" {
+			idComparison = "<"
+		}
+
+		// the cursor's placeholders follow the filter's
+		where += fmt.Sprintf(`
+    AND (%[1]s %[2]s $%[5]d::%[3]s OR (%[1]s = $%[5]d::%[3]s AND id %[4]s $%[6]d))`, column, comparison, columnType, idComparison, len(args)+1, len(args)+2)
+		args = append(args, cursor.Value, cursor.ID)
+	}
+
+	// the sort column is needed for the cursors, even if it wasn't asked for
+	fields := filters.Fields
+	if len(fields) > 0 && !slices.Contains(fields, column) {
+		fields = append(slices.Clip(fields), column)
+	}
+
+	// fetch one extra row to find out whether there's another page
+	query := fmt.Sprintf(`
+    SELECT %s
+    FROM movies
+    WHERE %s
+    ORDER BY %s %s, id %s
+    LIMIT $%d`, movieColumnList(fields), where, column, direction, idDirection, len(args)+1)
+
+	args = append(args, filters.limit()+1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	movies := []*Movie{}
+
+	for rows.Next() {
+		var movie Movie
+
+		_, dest := movieColumns(&movie, fields)
+
+		err := rows.Scan(dest...)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		movies = append(movies, &movie)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	hasMore := len(movies) > filters.limit()
+	if hasMore {
+		movies = movies[:filters.limit()]
+	}
+
+	if backward {
+		slices.Reverse(movies)
+	}
+
+	metadata := Metadata{PageSize: filters.PageSize}
+
+	if len(movies) > 0 {
+		first, last := movies[0], movies[len(movies)-1]
+
+		if (!backward && hasMore) || backward {
+			metadata.NextCursor = Cursor{Sort: filters.Sort, Value: movieSortValue(last, column), ID: last.ID}.Encode(filters.CursorKey)
+		}
+
+		if (backward && hasMore) || (!backward && cursor != nil) {
+			metadata.PrevCursor = Cursor{Sort: filters.Sort, Value: movieSortValue(first, column), ID: first.ID, Backward: true}.Encode(filters.CursorKey)
+		}
+	}
+
+	// counting every match is what makes deep pages slow, so it's opt-in
+	if filters.IncludeTotal {
+		query := fmt.Sprintf(`
+    SELECT count(*)
+    FROM movies
+    WHERE %s`, movieFilterClause)
+
+		err = m.DB.QueryRowContext(ctx, query, filter.args()...).Scan(&metadata.TotalRecords)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+	}
+
+	return movies, metadata, nil
+}
+
+func (m MovieModel) Update(movie *Movie, editorID int64) error {
 	query := `
     UPDATE movies
     SET title = $1, year = $2, runtime = $3, genres = $4, version = version + 1
-    WHERE id = $5 AND version = $6
+    WHERE id = $5 AND version = $6 AND deleted_at IS NULL
     RETURNING version`
 
 	args := []any{
@@ -150,7 +441,9 @@ func (m MovieModel) Update(movie *Movie) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&movie.Version)
+	err := m.asEditor(ctx, editorID, func(tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, query, args...).Scan(&movie.Version)
+	})
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -163,48 +456,293 @@ func (m MovieModel) Update(movie *Movie) error {
 	return nil
 }
 
-func (m MovieModel) Delete(id int64) error {
+// Delete moves a movie to the trash; it's purged for good after the retention period
+func (m MovieModel) Delete(id int64, editorID int64) error {
 	if id < 1 {
 		return ErrRecordNotFound
 	}
 
 	query := `
-    DELETE FROM movies
-    WHERE id = $1`
+    UPDATE movies
+    SET deleted_at = now(), version = version + 1
+    WHERE id = $1 AND deleted_at IS NULL`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	result, err := m.DB.ExecContext(ctx, query, id)
+	var rowsAffected int64
+
+	err := m.asEditor(ctx, editorID, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, query, id)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err = result.RowsAffected()
+		return err
+	})
 	if err != nil {
 		return err
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// DeleteVersion deletes a movie only if it's still at the given version
+func (m MovieModel) DeleteVersion(id int64, version int32, editorID int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+    UPDATE movies
+    SET deleted_at = now(), version = version + 1
+    WHERE id = $1 AND version = $2 AND deleted_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var rowsAffected int64
+
+	err := m.asEditor(ctx, editorID, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, query, id, version)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err = result.RowsAffected()
+		return err
+	})
 	if err != nil {
 		return err
 	}
 
 	if rowsAffected == 0 {
-		return ErrRecordNotFound
+		return ErrEditConflict
 	}
 
 	return nil
 }
 
-func ValidateMovie(v *validator.Validator, movie *Movie) {
-	v.Check(movie.Title != "", "title", "must be provided")
-	v.Check(len(movie.Title) <= 500, "title", "must not be more than 500 bytes long")
+// GetTrash lists deleted movies, most recently deleted first
+func (m MovieModel) GetTrash(filters Filters) ([]*Movie, Metadata, error) {
+	query := `
+    SELECT count(*) OVER(), id, created_at, title, year, runtime, genres, version, deleted_at
+    FROM movies
+    WHERE deleted_at IS NOT NULL
+    ORDER BY deleted_at DESC, id ASC
+    LIMIT $1 OFFSET $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	movies := []*Movie{}
+
+	for rows.Next() {
+		var movie Movie
+
+		err := rows.Scan(
+			&totalRecords,
+			&movie.ID,
+			&movie.CreatedAt,
+			&movie.Title,
+			&movie.Year,
+			&movie.Runtime,
+			pq.Array(&movie.Genres),
+			&movie.Version,
+			&movie.DeletedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		movies = append(movies, &movie)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return movies, metadata, nil
+}
+
+// Restore takes a movie back out of the trash
+func (m MovieModel) Restore(id int64, editorID int64) (*Movie, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+    UPDATE movies
+    SET deleted_at = NULL, version = version + 1
+    WHERE id = $1 AND deleted_at IS NOT NULL
+    RETURNING id, created_at, title, year, runtime, genres, version`
+
+	var movie Movie
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.asEditor(ctx, editorID, func(tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, query, id).Scan(
+			&movie.ID,
+			&movie.CreatedAt,
+			&movie.Title,
+			&movie.Year,
+			&movie.Runtime,
+			pq.Array(&movie.Genres),
+			&movie.Version,
+		)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &movie, nil
+}
+
+// Purge permanently deletes movies that have been in the trash for longer
+// than the retention period, and returns how many were deleted
+func (m MovieModel) Purge(retention time.Duration) (int64, error) {
+	query := `
+    DELETE FROM movies
+    WHERE deleted_at < $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, time.Now().Add(-retention))
+	if err != nil {
+		return 0, err
+	}
 
-	v.Check(movie.Year != 0, "year", "must be provided")
-	v.Check(movie.Year >= 1888, "year", "must be greater than 1888")
-	v.Check(movie.Year <= int32(time.Now().Year()), "year", "must not be in the future")
+	return result.RowsAffected()
+}
+
+// CountPurgeable returns how many movies Purge would delete
+func (m MovieModel) CountPurgeable(retention time.Duration) (int64, error) {
+	query := `
+    SELECT count(*)
+    FROM movies
+    WHERE deleted_at < $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var count int64
+
+	err := m.DB.QueryRowContext(ctx, query, time.Now().Add(-retention)).Scan(&count)
+	return count, err
+}
+
+func ValidateMovieFields(v *validator.Validator, fields, include []string) {
+	for _, field := range fields {
+		v.Check(validator.PermittedValue(field, MovieFieldSafeList...), "fields", validator.CodeNotPermitted, "invalid field value")
+	}
+	v.Check(validator.Unique(fields), "fields", validator.CodeDuplicate, "must not contain duplicate values")
+
+	for _, related := range include {
+		v.Check(validator.PermittedValue(related, MovieIncludeSafeList...), "include", validator.CodeNotPermitted, "invalid include value")
+	}
+	v.Check(validator.Unique(include), "include", validator.CodeDuplicate, "must not contain duplicate values")
+}
+
+func ValidateMovie(v *validator.Validator, movie *Movie, taxonomy Taxonomy) {
+	v.Check(movie.Title != "", "title", validator.CodeRequired, "must be provided")
+	v.Check(len(movie.Title) <= 500, "title", validator.CodeTooLong, "must not be more than 500 bytes long", 500)
+
+	v.Check(movie.Year != 0, "year", validator.CodeRequired, "must be provided")
+	v.Check(movie.Year >= 1888, "year", validator.CodeTooSmall, "must be greater than 1888", 1888)
+	v.Check(movie.Year <= int32(time.Now().Year()), "year", validator.CodeTooLarge, "must not be in the future", time.Now().Year())
+
+	v.Check(movie.Runtime != 0, "runtime", validator.CodeRequired, "must be provided")
+	v.Check(movie.Runtime > 0, "runtime", validator.CodeTooSmall, "must be a positive integer", 1)
+
+	v.Check(movie.Genres != nil, "genres", validator.CodeRequired, "must be provided")
+	v.Check(len(movie.Genres) >= 1, "genres", validator.CodeTooFew, "must contain at least 1 genre", 1)
+	v.Check(len(movie.Genres) <= 5, "genres", validator.CodeTooMany, "must not contain more than 5 genres", 5)
+	v.Check(validator.Unique(movie.Genres), "genres", validator.CodeDuplicate, "must not contain duplicate values")
+
+	for _, genre := range movie.Genres {
+		v.Check(taxonomy.Contains(genre), "genres", validator.CodeNotPermitted, fmt.Sprintf("%q is not a known genre", genre))
+	}
+}
+
+type MovieSuggestion struct {
+	ID    int64  `json:"id"`
+	Title string `json:"title"`
+	Year  int32  `json:"year"`
+}
+
+// escapes the LIKE wildcards in user input
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// Suggest returns up to limit movies whose title starts with the query, or has
+// a word that does; titles starting with the query are listed first
+func (m MovieModel) Suggest(q string, limit int) ([]*MovieSuggestion, error) {
+	query := `
+    SELECT id, title, year
+    FROM movies
+    WHERE (lower(title) LIKE $1 OR to_tsvector('simple', title) @@ to_tsquery('simple', $2))
+    AND deleted_at IS NULL
+    ORDER BY lower(title) LIKE $1 DESC, title ASC, id ASC
+    LIMIT $3`
+
+	pattern := likeEscaper.Replace(strings.ToLower(q)) + "%"
+	tsquery := MovieFilter{Title: q, SearchMode: SearchModePrefix}.tsquery()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pattern, tsquery, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suggestions := []*MovieSuggestion{}
+
+	for rows.Next() {
+		var suggestion MovieSuggestion
+
+		err := rows.Scan(&suggestion.ID, &suggestion.Title, &suggestion.Year)
+		if err != nil {
+			return nil, err
+		}
+
+		suggestions = append(suggestions, &suggestion)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return suggestions, nil
+}
 
-	v.Check(movie.Runtime != 0, "runtime", "must be provided")
-	v.Check(movie.Runtime > 0, "runtime", "must be a positive integer")
+func ValidateSuggest(v *validator.Validator, q string, limit int) {
+	v.Check(strings.TrimSpace(q) != "", "q", validator.CodeRequired, "must be provided")
+	v.Check(len(q) <= 100, "q", validator.CodeTooLong, "must not be more than 100 bytes long", 100)
 
-	v.Check(movie.Genres != nil, "genres", "must be provided")
-	v.Check(len(movie.Genres) >= 1, "genres", "must contain at least 1 genre")
-	v.Check(len(movie.Genres) <= 5, "genres", "must not contain more than 5 genres")
-	v.Check(validator.Unique(movie.Genres), "genres", "must not contain duplicate values")
+	v.Check(limit > 0, "limit", validator.CodeTooSmall, "must be greater than zero", 1)
+	v.Check(limit <= 20, "limit", validator.CodeTooLarge, "must be a maximum of 20", 20)
 }
@@ -63,7 +63,8 @@ func (m PermissionModel) GetAllForUsers(userID int64) (Permissions, error) {
 func (m PermissionModel) AddForUser(userID int64, codes ...string) error {
 	query := `
     INSERT INTO users_permissions
-    SELECT $1, permissions.id FROM permissions WHERE permissions.code = ANY($2)`
+    SELECT $1, permissions.id FROM permissions WHERE permissions.code = ANY($2)
+    ON CONFLICT DO NOTHING`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -71,3 +72,60 @@ func (m PermissionModel) AddForUser(userID int64, codes ...string) error {
 	_, err := m.DB.ExecContext(ctx, query, userID, pq.Array(codes))
 	return err
 }
+
+// GetAll returns the codes of every permission
+func (m PermissionModel) GetAll() (Permissions, error) {
+	query := `
+    SELECT code
+    FROM permissions
+    ORDER BY code`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var permissions Permissions
+
+	for rows.Next() {
+		var permission string
+
+		err := rows.Scan(&permission)
+		if err != nil {
+			return nil, err
+		}
+
+		permissions = append(permissions, permission)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return permissions, nil
+}
+
+// RemoveForUser takes permissions away from a user, and returns how many the
+// user had
+func (m PermissionModel) RemoveForUser(userID int64, codes ...string) (int64, error) {
+	query := `
+    DELETE FROM users_permissions
+    USING permissions
+    WHERE users_permissions.permission_id = permissions.id
+    AND users_permissions.user_id = $1
+    AND permissions.code = ANY($2)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userID, pq.Array(codes))
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
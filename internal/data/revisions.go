@@ -0,0 +1,194 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Revision is a snapshot of a movie at one of its versions
+type Revision struct {
+	MovieID   int64     `json:"movie_id"`
+	Version   int32     `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	UserID    *int64    `json:"user_id"`
+	Title     string    `json:"title"`
+	Year      int32     `json:"year"`
+	Runtime   Runtime   `json:"runtime"`
+	Genres    []string  `json:"genres"`
+	Deleted   bool      `json:"deleted"`
+}
+
+// RevisionChange holds the old and new value of a field that differs between two revisions
+type RevisionChange struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+type RevisionModel struct {
+	DB *sql.DB
+}
+
+// asEditor runs fn in a transaction, attributing the movie revisions
+// it creates to the editor. revisions are recorded by a trigger, which
+// reads the editor from the transaction-local greenlight.user_id setting
+func (m MovieModel) asEditor(ctx context.Context, editorID int64, fn func(tx *sql.Tx) error) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = setEditor(ctx, tx, editorID)
+	if err != nil {
+		return err
+	}
+
+	err = fn(tx)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// setEditor attributes the movie revisions a transaction creates to the editor,
+// unless the ID is 0
+func setEditor(ctx context.Context, tx *sql.Tx, editorID int64) error {
+	if editorID < 1 {
+		return nil
+	}
+
+	_, err := tx.ExecContext(ctx, `SELECT set_config('greenlight.user_id', $1, true)`, strconv.FormatInt(editorID, 10))
+	return err
+}
+
+// GetAllForMovie lists the revisions of a movie, newest first. movies in the
+// trash have none, like they don't exist
+func (m RevisionModel) GetAllForMovie(movieID int64, filters Filters) ([]*Revision, Metadata, error) {
+	query := `
+    SELECT count(*) OVER(), movie_revisions.movie_id, movie_revisions.version, movie_revisions.created_at,
+      movie_revisions.user_id, movie_revisions.title, movie_revisions.year, movie_revisions.runtime,
+      movie_revisions.genres, movie_revisions.deleted
+    FROM movie_revisions
+    INNER JOIN movies ON movies.id = movie_revisions.movie_id
+    WHERE movie_revisions.movie_id = $1 AND movies.deleted_at IS NULL
+    ORDER BY movie_revisions.version DESC
+    LIMIT $2 OFFSET $3`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, movieID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	revisions := []*Revision{}
+
+	for rows.Next() {
+		var revision Revision
+
+		err := rows.Scan(
+			&totalRecords,
+			&revision.MovieID,
+			&revision.Version,
+			&revision.CreatedAt,
+			&revision.UserID,
+			&revision.Title,
+			&revision.Year,
+			&revision.Runtime,
+			pq.Array(&revision.Genres),
+			&revision.Deleted,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		revisions = append(revisions, &revision)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return revisions, metadata, nil
+}
+
+// Get returns a revision of a movie, unless the movie is in the trash
+func (m RevisionModel) Get(movieID int64, version int32) (*Revision, error) {
+	if movieID < 1 || version < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+    SELECT movie_revisions.movie_id, movie_revisions.version, movie_revisions.created_at,
+      movie_revisions.user_id, movie_revisions.title, movie_revisions.year, movie_revisions.runtime,
+      movie_revisions.genres, movie_revisions.deleted
+    FROM movie_revisions
+    INNER JOIN movies ON movies.id = movie_revisions.movie_id
+    WHERE movie_revisions.movie_id = $1 AND movie_revisions.version = $2 AND movies.deleted_at IS NULL`
+
+	var revision Revision
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, movieID, version).Scan(
+		&revision.MovieID,
+		&revision.Version,
+		&revision.CreatedAt,
+		&revision.UserID,
+		&revision.Title,
+		&revision.Year,
+		&revision.Runtime,
+		pq.Array(&revision.Genres),
+		&revision.Deleted,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &revision, nil
+}
+
+// DiffRevisions returns the fields that changed between two revisions
+func DiffRevisions(from, to *Revision) map[string]RevisionChange {
+	changes := make(map[string]RevisionChange)
+
+	if from.Title != to.Title {
+		changes["title"] = RevisionChange{From: from.Title, To: to.Title}
+	}
+
+	if from.Year != to.Year {
+		changes["year"] = RevisionChange{From: from.Year, To: to.Year}
+	}
+
+	if from.Runtime != to.Runtime {
+		changes["runtime"] = RevisionChange{From: from.Runtime, To: to.Runtime}
+	}
+
+	if !slices.Equal(from.Genres, to.Genres) {
+		changes["genres"] = RevisionChange{From: from.Genres, To: to.Genres}
+	}
+
+	if from.Deleted != to.Deleted {
+		changes["deleted"] = RevisionChange{From: from.Deleted, To: to.Deleted}
+	}
+
+	return changes
+}
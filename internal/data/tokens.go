@@ -29,8 +29,8 @@ type TokenModel struct {
 }
 
 func ValidateTokenPlaintext(v *validator.Validator, tokenPlaintext string) {
-	v.Check(tokenPlaintext != "", "token", "must be provided")
-	v.Check(len(tokenPlaintext) == 26, "token", "must be 26 bytes long")
+	v.Check(tokenPlaintext != "", "token", validator.CodeRequired, "must be provided")
+	v.Check(len(tokenPlaintext) == 26, "token", validator.CodeInvalid, "must be 26 bytes long")
 }
 
 func generateToken(userID int64, tll time.Duration, scope string) (*Token, error) {
@@ -96,3 +96,43 @@ func (m TokenModel) DeleteAllForUser(scope string, userID int64) error {
 	_, err := m.DB.ExecContext(ctx, query, scope, userID)
 	return err
 }
+
+// CountExpired returns how many tokens have expired
+func (m TokenModel) CountExpired() (int64, error) {
+	query := `
+    SELECT count(*)
+    FROM tokens
+    WHERE expiry < now()`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var count int64
+
+	err := m.DB.QueryRowContext(ctx, query).Scan(&count)
+	return count, err
+}
+
+// DeleteExpired deletes up to limit tokens that have expired, and returns how
+// many were deleted. deleting in batches keeps the transactions short, so
+// callers repeat it until it deletes fewer than limit
+func (m TokenModel) DeleteExpired(limit int) (int64, error) {
+	query := `
+    DELETE FROM tokens
+    WHERE hash IN (
+      SELECT hash
+      FROM tokens
+      WHERE expiry < now()
+      LIMIT $1
+    )`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
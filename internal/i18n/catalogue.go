@@ -0,0 +1,116 @@
+package i18n
+
+import "github.com/ildx/greenlight/internal/validator"
+
+// catalogue holds the translated messages by language and error code. messages
+// use explicit argument indexes, with the arguments documented with each code
+var catalogue = map[string]map[string]string{
+	"de": {
+		validator.CodeRequired:      "muss angegeben werden",
+		validator.CodeInvalid:       "ist ungültig",
+		validator.CodeInvalidToken:  "ungültiges oder abgelaufenes Token",
+		validator.CodeNotPermitted:  "ist kein zulässiger Wert",
+		validator.CodeTooShort:      "muss mindestens %[1]v Bytes lang sein",
+		validator.CodeTooLong:       "darf höchstens %[1]v Bytes lang sein",
+		validator.CodeTooSmall:      "muss mindestens %[1]v sein",
+		validator.CodeTooLarge:      "darf höchstens %[1]v sein",
+		validator.CodeOutOfRange:    "muss zwischen %[1]v und %[2]v liegen",
+		validator.CodeTooFew:        "muss mindestens %[1]v Einträge enthalten",
+		validator.CodeTooMany:       "darf höchstens %[1]v Einträge enthalten",
+		validator.CodeDuplicate:     "darf keine doppelten Werte enthalten",
+		validator.CodeConflict:      "widerspricht anderen Angaben oder dem aktuellen Stand",
+		validator.CodeAlreadyExists: "existiert bereits",
+		validator.CodeNotFound:      "existiert nicht",
+		validator.CodeInUse:         "wird noch verwendet",
+
+		"import.not_saved":      "konnte nicht gespeichert werden",
+		"import.row_rejected":   "nicht gespeichert, weil Zeile %[1]v desselben Stapels abgelehnt wurde",
+		"import.field_rejected": "nicht gespeichert, weil eine Zeile desselben Stapels ein ungültiges Feld %[1]v hat",
+
+		"failed_validation":            "die Anfrage enthält ungültige Felder",
+		"not_found":                    "die angeforderte Ressource wurde nicht gefunden",
+		"method_not_allowed":           "die Methode %[1]s wird für diese Ressource nicht unterstützt",
+		"not_acceptable":               "die Ressource ist in keinem der akzeptierten Formate verfügbar",
+		"edit_conflict":                "der Datensatz konnte wegen einer gleichzeitigen Änderung nicht aktualisiert werden, bitte erneut versuchen",
+		"precondition_failed":          "die Ressource wurde seit dem Abruf geändert, bitte erneut abrufen und wiederholen",
+		"rate_limit_exceeded":          "zu viele Anfragen",
+		"invalid_credentials":          "ungültige Anmeldedaten",
+		"invalid_authentication_token": "ungültiges oder fehlendes Authentifizierungstoken",
+		"authentication_required":      "für diese Ressource ist eine Anmeldung erforderlich",
+		"inactive_account":             "für diese Ressource muss das Benutzerkonto aktiviert sein",
+		"not_permitted":                "das Benutzerkonto hat nicht die nötigen Berechtigungen für diese Ressource",
+		"server_error":                 "der Server konnte die Anfrage wegen eines Problems nicht verarbeiten",
+	},
+	"fr": {
+		validator.CodeRequired:      "doit être renseigné",
+		validator.CodeInvalid:       "n'est pas valide",
+		validator.CodeInvalidToken:  "jeton invalide ou expiré",
+		validator.CodeNotPermitted:  "n'est pas une valeur autorisée",
+		validator.CodeTooShort:      "doit faire au moins %[1]v octets",
+		validator.CodeTooLong:       "ne doit pas dépasser %[1]v octets",
+		validator.CodeTooSmall:      "doit être au moins %[1]v",
+		validator.CodeTooLarge:      "doit être au plus %[1]v",
+		validator.CodeOutOfRange:    "doit être compris entre %[1]v et %[2]v",
+		validator.CodeTooFew:        "doit contenir au moins %[1]v éléments",
+		validator.CodeTooMany:       "ne doit pas contenir plus de %[1]v éléments",
+		validator.CodeDuplicate:     "ne doit pas contenir de doublons",
+		validator.CodeConflict:      "est en contradiction avec d'autres champs ou l'état actuel",
+		validator.CodeAlreadyExists: "existe déjà",
+		validator.CodeNotFound:      "n'existe pas",
+		validator.CodeInUse:         "est encore utilisé",
+
+		"import.not_saved":      "n'a pas pu être enregistré",
+		"import.row_rejected":   "non enregistré, car la ligne %[1]v du même lot a été rejetée",
+		"import.field_rejected": "non enregistré, car une ligne du même lot a un champ %[1]v invalide",
+
+		"failed_validation":            "la requête contient des champs invalides",
+		"not_found":                    "la ressource demandée est introuvable",
+		"method_not_allowed":           "la méthode %[1]s n'est pas prise en charge pour cette ressource",
+		"not_acceptable":               "la ressource n'est disponible dans aucun des formats acceptés",
+		"edit_conflict":                "impossible de mettre à jour l'enregistrement à cause d'une modification concurrente, veuillez réessayer",
+		"precondition_failed":          "la ressource a été modifiée depuis sa récupération, récupérez-la à nouveau et réessayez",
+		"rate_limit_exceeded":          "trop de requêtes",
+		"invalid_credentials":          "identifiants invalides",
+		"invalid_authentication_token": "jeton d'authentification invalide ou manquant",
+		"authentication_required":      "vous devez être authentifié pour accéder à cette ressource",
+		"inactive_account":             "votre compte doit être activé pour accéder à cette ressource",
+		"not_permitted":                "votre compte n'a pas les autorisations nécessaires pour accéder à cette ressource",
+		"server_error":                 "le serveur a rencontré un problème et n'a pas pu traiter votre requête",
+	},
+	"es": {
+		validator.CodeRequired:      "es obligatorio",
+		validator.CodeInvalid:       "no es válido",
+		validator.CodeInvalidToken:  "token no válido o caducado",
+		validator.CodeNotPermitted:  "no es un valor permitido",
+		validator.CodeTooShort:      "debe tener al menos %[1]v bytes",
+		validator.CodeTooLong:       "no debe superar los %[1]v bytes",
+		validator.CodeTooSmall:      "debe ser como mínimo %[1]v",
+		validator.CodeTooLarge:      "debe ser como máximo %[1]v",
+		validator.CodeOutOfRange:    "debe estar entre %[1]v y %[2]v",
+		validator.CodeTooFew:        "debe contener al menos %[1]v elementos",
+		validator.CodeTooMany:       "no debe contener más de %[1]v elementos",
+		validator.CodeDuplicate:     "no debe contener valores duplicados",
+		validator.CodeConflict:      "contradice otros campos o el estado actual",
+		validator.CodeAlreadyExists: "ya existe",
+		validator.CodeNotFound:      "no existe",
+		validator.CodeInUse:         "todavía está en uso",
+
+		"import.not_saved":      "no se ha podido guardar",
+		"import.row_rejected":   "no se ha guardado porque la fila %[1]v del mismo lote fue rechazada",
+		"import.field_rejected": "no se ha guardado porque una fila del mismo lote tiene un campo %[1]v no válido",
+
+		"failed_validation":            "la solicitud contiene campos no válidos",
+		"not_found":                    "no se ha encontrado el recurso solicitado",
+		"method_not_allowed":           "el método %[1]s no está admitido para este recurso",
+		"not_acceptable":               "el recurso no está disponible en ninguno de los formatos aceptados",
+		"edit_conflict":                "no se ha podido actualizar el registro por una modificación simultánea, inténtelo de nuevo",
+		"precondition_failed":          "el recurso ha cambiado desde que se obtuvo, vuelva a obtenerlo e inténtelo de nuevo",
+		"rate_limit_exceeded":          "demasiadas solicitudes",
+		"invalid_credentials":          "credenciales no válidas",
+		"invalid_authentication_token": "token de autenticación no válido o ausente",
+		"authentication_required":      "debe autenticarse para acceder a este recurso",
+		"inactive_account":             "su cuenta debe estar activada para acceder a este recurso",
+		"not_permitted":                "su cuenta no tiene los permisos necesarios para acceder a este recurso",
+		"server_error":                 "el servidor ha tenido un problema y no ha podido procesar su solicitud",
+	},
+}
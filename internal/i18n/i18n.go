@@ -0,0 +1,90 @@
+package i18n
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/ildx/greenlight/internal/validator"
+)
+
+// English is the language of the messages in the code, and the fallback
+// for everything the catalogue doesn't translate
+const English = "en"
+
+// Languages lists the supported languages
+var Languages = []string{English, "de", "fr", "es"}
+
+// Negotiate picks the supported language that an Accept-Language header
+// prefers, matching on the primary subtag, so "de-CH" selects German. between
+// languages with the same q-value, the first one in the header wins
+func Negotiate(header string) string {
+	best, bestQ := English, 0.0
+
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			q, err = strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+		}
+
+		primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if primary == "*" {
+			primary = English
+		}
+
+		if _, ok := catalogue[primary]; (ok || primary == English) && q > bestQ {
+			best, bestQ = primary, q
+		}
+	}
+
+	return best
+}
+
+// Message returns the translation of the message for an error code, formatted
+// with args. codes without a translation keep the English message
+func Message(lang, code, english string, args ...any) string {
+	translated, ok := catalogue[lang][code]
+	if !ok {
+		return english
+	}
+
+	return validator.Format(translated, args...)
+}
+
+// Errors translates the error messages of a validator
+func Errors(lang string, v *validator.Validator) map[string]string {
+	if lang == English {
+		return v.Errors
+	}
+
+	errors := make(map[string]string, len(v.Errors))
+	for key, message := range v.Errors {
+		errors[key] = Message(lang, v.Codes[key], message, v.Args[key]...)
+	}
+
+	return errors
+}
+
+// Grouped translates the grouped errors of a validator
+func Grouped(lang string, v *validator.Validator) []validator.FieldErrors {
+	grouped := v.Grouped()
+	if lang == English {
+		return grouped
+	}
+
+	for i, field := range grouped {
+		errors := make([]validator.FieldError, len(field.Errors))
+		for j, e := range field.Errors {
+			e.Message = Message(lang, e.Code, e.Message, e.Args...)
+			errors[j] = e
+		}
+		grouped[i].Errors = errors
+	}
+
+	return grouped
+}
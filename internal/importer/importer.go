@@ -0,0 +1,355 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/ildx/greenlight/internal/data"
+	"github.com/ildx/greenlight/internal/i18n"
+	"github.com/ildx/greenlight/internal/validator"
+)
+
+// supported input formats
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+// row statuses in the report
+const (
+	StatusCreated = "created"
+	StatusValid   = "valid" // passed validation in a dry run
+	StatusInvalid = "invalid"
+	StatusFailed  = "failed" // valid, but the database rejected its batch
+)
+
+// codes of the errors of rows the database rejected, next to the validator's
+const (
+	CodeNotSaved      = "import.not_saved"      // the database rejected the batch of the row
+	CodeRowRejected   = "import.row_rejected"   // another row of the batch was rejected; args: that row
+	CodeFieldRejected = "import.field_rejected" // another row of the batch was rejected; args: the field at fault
+)
+
+// upper bound on the lines of NDJSON input, per line
+const maxLineBytes = 64 * 1024
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported import format")
+
+	// error for input that can't be imported at all, as opposed to invalid rows
+	ErrInvalidInput = errors.New("invalid input")
+)
+
+// Options control an import
+type Options struct {
+	Format    string
+	DryRun    bool
+	BatchSize int
+	EditorID  int64
+	Language  string // of the errors in the report, one of i18n.Languages; English by default
+}
+
+// RowResult is the outcome for a single row; rows are numbered from 1,
+// not counting the CSV header
+type RowResult struct {
+	Row        int                     `json:"row"`
+	Status     string                  `json:"status"`
+	Title      string                  `json:"title,omitempty"`
+	Errors     map[string]string       `json:"errors,omitempty"`
+	FieldCodes map[string]string       `json:"field_codes,omitempty"`
+	Details    []validator.FieldErrors `json:"details,omitempty"` // every error, grouped by field
+}
+
+// Report summarizes an import
+type Report struct {
+	DryRun  bool         `json:"dry_run"`
+	Total   int          `json:"total"`
+	Created int          `json:"created"`
+	Valid   int          `json:"valid"`
+	Invalid int          `json:"invalid"`
+	Failed  int          `json:"failed"`
+	Rows    []*RowResult `json:"rows"`
+}
+
+// Importer validates movies and inserts them in batches
+type Importer struct {
+	Models data.Models
+	Logger *slog.Logger // for database errors, which reports leave out
+}
+
+// New returns an importer. logger may be nil
+func New(models data.Models, logger *slog.Logger) Importer {
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	return Importer{Models: models, Logger: logger}
+}
+
+// Run reads movies from r, validates every row with data.ValidateMovie and
+// inserts the valid ones in batched transactions. invalid rows don't stop
+// the import; they are listed in the report with their validation errors.
+// the whole input is read and validated before anything is inserted, so
+// input that can't be read, like a line that is too long, fails the import
+// without leaving part of it in the database
+func (i Importer) Run(r io.Reader, opts Options) (*Report, error) {
+	if opts.BatchSize < 1 {
+		opts.BatchSize = 500
+	}
+	if opts.Language == "" {
+		opts.Language = i18n.English
+	}
+
+	taxonomy, err := i.Models.Genres.Taxonomy()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{DryRun: opts.DryRun, Rows: []*RowResult{}}
+
+	// the valid movies, and the rows they came from
+	var movies []*data.Movie
+	var results []*RowResult
+
+	err = parse(r, opts.Format, func(row int, movie *data.Movie, parseErr error) {
+		report.Total++
+
+		result := &RowResult{Row: row}
+		report.Rows = append(report.Rows, result)
+
+		v := validator.New()
+		v.CollectAll = true
+
+		if parseErr != nil {
+			v.AddError("row", validator.CodeInvalid, parseErr.Error())
+		} else {
+			result.Title = movie.Title
+			movie.Genres = taxonomy.Canonicalize(movie.Genres)
+			data.ValidateMovie(v, movie, taxonomy)
+		}
+
+		if !v.Valid() {
+			result.Status = StatusInvalid
+			result.setErrors(opts.Language, v)
+			report.Invalid++
+			return
+		}
+
+		if opts.DryRun {
+			result.Status = StatusValid
+			report.Valid++
+			return
+		}
+
+		movies = append(movies, movie)
+		results = append(results, result)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for start := 0; start < len(movies); start += opts.BatchSize {
+		end := min(start+opts.BatchSize, len(movies))
+
+		err := i.Models.Movies.InsertBatch(movies[start:end], opts.EditorID)
+		if err != nil {
+			i.failBatch(report, results[start:end], err, opts.Language)
+			continue
+		}
+
+		for _, result := range results[start:end] {
+			result.Status = StatusCreated
+			report.Created++
+		}
+	}
+
+	return report, nil
+}
+
+// failBatch marks the rows of a batch the database rejected as failed. a
+// movie that breaks a constraint gets the validation error it stands for;
+// other errors are logged rather than reported, since they are about the
+// database, not the input
+func (i Importer) failBatch(report *Report, results []*RowResult, err error, lang string) {
+	var constraintErr *data.MovieConstraintError
+	if !errors.As(err, &constraintErr) {
+		i.Logger.Error("importing movies", "error", err.Error(), "rows", len(results))
+	}
+
+	for index, result := range results {
+		result.Status = StatusFailed
+		report.Failed++
+
+		v := validator.New()
+
+		switch {
+		case constraintErr == nil:
+			v.AddError("database", CodeNotSaved, "could not be saved")
+		case constraintErr.Index == index:
+			v.AddError(constraintErr.Field, constraintErr.Code, constraintErr.Message, constraintErr.Args...)
+		case constraintErr.Index >= 0 && constraintErr.Index < len(results):
+			row := results[constraintErr.Index].Row
+			v.AddError("database", CodeRowRejected, fmt.Sprintf("not saved, because row %d of the same batch was rejected", row), row)
+		default:
+			v.AddError("database", CodeFieldRejected, fmt.Sprintf("not saved, because a row of the same batch has an invalid %s", constraintErr.Field), constraintErr.Field)
+		}
+
+		result.setErrors(lang, v)
+	}
+}
+
+// setErrors reports the errors of a validator, in the given language
+func (r *RowResult) setErrors(lang string, v *validator.Validator) {
+	r.Errors = i18n.Errors(lang, v)
+	r.FieldCodes = v.Codes
+	r.Details = i18n.Grouped(lang, v)
+}
+
+// parse calls fn for every row of the input, with either the movie
+// or the reason the row couldn't be read
+func parse(r io.Reader, format string, fn func(row int, movie *data.Movie, err error)) error {
+	switch format {
+	case FormatCSV:
+		return parseCSV(r, fn)
+	case FormatNDJSON:
+		return parseNDJSON(r, fn)
+	default:
+		return ErrUnsupportedFormat
+	}
+}
+
+// CSV input has a header row naming the title, year, runtime and genres
+// columns, in any order. runtimes are either "102" or "102 mins", and genres
+// are separated by "|"
+func parseCSV(r io.Reader, fn func(row int, movie *data.Movie, err error)) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return fmt.Errorf("%w: input must not be empty", ErrInvalidInput)
+		}
+		return fmt.Errorf("%w: invalid CSV header: %s", ErrInvalidInput, err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	for _, name := range []string{"title", "year", "runtime", "genres"} {
+		if _, ok := columns[name]; !ok {
+			return fmt.Errorf("%w: CSV header is missing the %q column", ErrInvalidInput, name)
+		}
+	}
+
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			fn(row, nil, parseErr.Err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		field := func(name string) string {
+			if i := columns[name]; i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		movie := &data.Movie{Title: field("title")}
+
+		if s := field("year"); s != "" {
+			year, err := strconv.ParseInt(s, 10, 32)
+			if err != nil {
+				fn(row, nil, errors.New("year must be an integer"))
+				continue
+			}
+			movie.Year = int32(year)
+		}
+
+		if s := strings.TrimSuffix(field("runtime"), " mins"); s != "" {
+			runtime, err := strconv.ParseInt(s, 10, 32)
+			if err != nil {
+				fn(row, nil, data.ErrInvalidRuntimeFormat)
+				continue
+			}
+			movie.Runtime = data.Runtime(runtime)
+		}
+
+		if s := field("genres"); s != "" {
+			movie.Genres = strings.Split(s, "|")
+			for i := range movie.Genres {
+				movie.Genres[i] = strings.TrimSpace(movie.Genres[i])
+			}
+		}
+
+		fn(row, movie, nil)
+	}
+}
+
+// NDJSON input has one movie per line, in the same JSON format the API accepts
+func parseNDJSON(r io.Reader, fn func(row int, movie *data.Movie, err error)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), maxLineBytes)
+
+	row := 0
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		row++
+
+		var input struct {
+			Title   string       `json:"title"`
+			Year    int32        `json:"year"`
+			Runtime data.Runtime `json:"runtime"`
+			Genres  []string     `json:"genres"`
+		}
+
+		dec := json.NewDecoder(bytes.NewReader(line))
+		dec.DisallowUnknownFields()
+
+		err := dec.Decode(&input)
+		if err != nil {
+			fn(row, nil, fmt.Errorf("invalid JSON: %w", err))
+			continue
+		}
+
+		fn(row, &data.Movie{
+			Title:   input.Title,
+			Year:    input.Year,
+			Runtime: input.Runtime,
+			Genres:  input.Genres,
+		}, nil)
+	}
+
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return fmt.Errorf("%w: line %d is longer than %d bytes", ErrInvalidInput, row+1, maxLineBytes)
+		}
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,393 @@
+// Package migrate applies the SQL migrations of the database. it keeps track
+// of the current version in the same schema_migrations table as the migrate
+// tool, so databases migrated with either one can be migrated with the other
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// key of the advisory lock held while migrating, so that instances starting at
+// the same time don't migrate at the same time
+const lockKey int64 = 0x67726e6c6d6967 // "grnlmig"
+
+var (
+	// ErrNoChange is returned when the database is already at the requested version
+	ErrNoChange = errors.New("migrate: no change")
+
+	// ErrUnknownVersion is returned for versions without a migration
+	ErrUnknownVersion = errors.New("migrate: unknown version")
+)
+
+// DirtyError is returned when a previous migration failed halfway. the database
+// has to be fixed by hand, and its version set with Force
+type DirtyError struct {
+	Version int
+}
+
+func (e *DirtyError) Error() string {
+	return fmt.Sprintf("migrate: database is dirty at version %d; fix it and force a version", e.Version)
+}
+
+// Migration is a pair of up and down migrations
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Migrator applies migrations to a database
+type Migrator struct {
+	db         *sql.DB
+	migrations []*Migration // by version
+	logger     *slog.Logger
+}
+
+// NNNNNN_name.up.sql or NNNNNN_name.down.sql
+var fileRX = regexp.MustCompile(`^([0-9]+)_(.+)\.(up|down)\.sql$`)
+
+// New returns a migrator for the migrations in the root of fsys. every
+// migration needs an up and a down file. logger may be nil
+func New(db *sql.DB, fsys fs.FS, logger *slog.Logger) (*Migrator, error) {
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+
+	for _, file := range files {
+		match := fileRX.FindStringSubmatch(path.Base(file))
+		if match == nil {
+			return nil, fmt.Errorf("migrate: invalid migration file name %q", file)
+		}
+
+		version, err := strconv.Atoi(match[1])
+		if err != nil || version < 1 {
+			return nil, fmt.Errorf("migrate: invalid migration version in %q", file)
+		}
+
+		b, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migrate: version %d has migrations named %q and %q", version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = string(b)
+		} else {
+			m.Down = string(b)
+		}
+	}
+
+	migrator := &Migrator{db: db, logger: logger}
+
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migrate: version %d needs both an up and a down migration", m.Version)
+		}
+		migrator.migrations = append(migrator.migrations, m)
+	}
+
+	sort.Slice(migrator.migrations, func(i, j int) bool {
+		return migrator.migrations[i].Version < migrator.migrations[j].Version
+	})
+
+	return migrator, nil
+}
+
+// Status describes the version of the database and the migrations
+type Status struct {
+	Version    int  // 0 before the first migration
+	Dirty      bool // whether the migration to Version failed halfway
+	Migrations []MigrationStatus
+}
+
+// MigrationStatus tells whether a migration has been applied
+type MigrationStatus struct {
+	Version int
+	Name    string
+	Applied bool
+}
+
+// Status returns the version of the database and which migrations it has
+func (m *Migrator) Status(ctx context.Context) (*Status, error) {
+	var status *Status
+
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		version, dirty, err := currentVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		status = &Status{Version: version, Dirty: dirty}
+		for _, migration := range m.migrations {
+			status.Migrations = append(status.Migrations, MigrationStatus{
+				Version: migration.Version,
+				Name:    migration.Name,
+				Applied: migration.Version <= version,
+			})
+		}
+
+		return nil
+	})
+
+	return status, err
+}
+
+// Up applies the next n migrations, or all of them if n is 0, and returns how
+// many it applied
+func (m *Migrator) Up(ctx context.Context, n int) (int, error) {
+	return m.migrate(ctx, func(version int) (int, error) {
+		i := m.index(version) + 1
+		if n <= 0 || i+n > len(m.migrations) {
+			n = len(m.migrations) - i
+		}
+		if n == 0 {
+			return version, nil
+		}
+		return m.migrations[i+n-1].Version, nil
+	})
+}
+
+// Down reverts the last n migrations, or all of them if n is 0, and returns
+// how many it reverted
+func (m *Migrator) Down(ctx context.Context, n int) (int, error) {
+	return m.migrate(ctx, func(version int) (int, error) {
+		i := m.index(version)
+		if n <= 0 || n > i+1 {
+			n = i + 1
+		}
+		if i-n < 0 {
+			return 0, nil
+		}
+		return m.migrations[i-n].Version, nil
+	})
+}
+
+// Goto migrates up or down to the given version, or reverts every migration
+// for version 0, and returns how many migrations it applied or reverted
+func (m *Migrator) Goto(ctx context.Context, version int) (int, error) {
+	if version != 0 && m.find(version) == nil {
+		return 0, fmt.Errorf("%w %d", ErrUnknownVersion, version)
+	}
+
+	return m.migrate(ctx, func(int) (int, error) {
+		return version, nil
+	})
+}
+
+// Force sets the version of the database without running any migrations,
+// clearing the dirty flag, after a failed migration was fixed by hand. version
+// 0 means that no migration has been applied
+func (m *Migrator) Force(ctx context.Context, version int) error {
+	if version != 0 && m.find(version) == nil {
+		return fmt.Errorf("%w %d", ErrUnknownVersion, version)
+	}
+
+	return m.locked(ctx, func(conn *sql.Conn) error {
+		m.logger.Info("forcing migration version", "version", version)
+		return setVersion(ctx, conn, version, false)
+	})
+}
+
+// migrate moves the database to the version target returns for the current
+// one, one migration at a time, and returns how many migrations it ran
+func (m *Migrator) migrate(ctx context.Context, target func(version int) (int, error)) (int, error) {
+	steps := 0
+
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		version, dirty, err := currentVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return &DirtyError{Version: version}
+		}
+		if version != 0 && m.find(version) == nil {
+			return fmt.Errorf("%w %d: the database is newer than the migrations", ErrUnknownVersion, version)
+		}
+
+		to, err := target(version)
+		if err != nil {
+			return err
+		}
+		if to == version {
+			return ErrNoChange
+		}
+
+		for version < to {
+			next := m.migrations[m.index(version)+1]
+
+			err = m.run(ctx, conn, next.Version, next.Up, "applying migration", next)
+			if err != nil {
+				return err
+			}
+
+			version = next.Version
+			steps++
+		}
+
+		for version > to {
+			current := m.migrations[m.index(version)]
+
+			previous := 0
+			if i := m.index(version); i > 0 {
+				previous = m.migrations[i-1].Version
+			}
+
+			err = m.run(ctx, conn, previous, current.Down, "reverting migration", current)
+			if err != nil {
+				return err
+			}
+
+			version = previous
+			steps++
+		}
+
+		return nil
+	})
+
+	return steps, err
+}
+
+// run runs the SQL of a migration, which takes the database to version to.
+// the database is marked dirty at the new version until the migration
+// succeeds, like the migrate tool does
+func (m *Migrator) run(ctx context.Context, conn *sql.Conn, to int, query, message string, migration *Migration) error {
+	m.logger.Info(message, "version", migration.Version, "name", migration.Name)
+
+	err := setVersion(ctx, conn, to, true)
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.ExecContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("migrate: %s %d_%s: %w", message, migration.Version, migration.Name, err)
+	}
+
+	return setVersion(ctx, conn, to, false)
+}
+
+// locked runs fn on a connection that holds the migration lock, waiting for
+// other instances to release it first
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	// advisory locks belong to a session, so the lock and the migrations all
+	// need the same connection
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockKey)
+	if err != nil {
+		return fmt.Errorf("migrate: acquiring lock: %w", err)
+	}
+
+	defer func() {
+		// the lock goes away with the session anyway, so a failed unlock
+		// only matters if the connection is reused
+		_, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey)
+		if err != nil {
+			conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+	}()
+
+	_, err = conn.ExecContext(ctx, `
+    CREATE TABLE IF NOT EXISTS schema_migrations (
+      version bigint NOT NULL PRIMARY KEY,
+      dirty boolean NOT NULL
+    )`)
+	if err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+// currentVersion returns the version of the database, 0 if no migration has
+// been applied, and whether it's dirty
+func currentVersion(ctx context.Context, conn *sql.Conn) (int, bool, error) {
+	var version int
+	var dirty bool
+
+	err := conn.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+
+	return max(version, 0), dirty, err
+}
+
+// setVersion records the version of the database
+func setVersion(ctx context.Context, conn *sql.Conn, version int, dirty bool) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM schema_migrations`)
+	if err != nil {
+		return err
+	}
+
+	// while the first migration is reverted, the database is dirty without a
+	// version, which the migrate tool writes as -1
+	if version == 0 && dirty {
+		version = -1
+	}
+
+	if version != 0 {
+		_, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES ($1, $2)`, version, dirty)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// index returns the position of a version in the migrations, or -1 for 0
+func (m *Migrator) index(version int) int {
+	for i, migration := range m.migrations {
+		if migration.Version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+// find returns the migration with the given version, if any
+func (m *Migrator) find(version int) *Migration {
+	if i := m.index(version); i >= 0 {
+		return m.migrations[i]
+	}
+	return nil
+}
@@ -0,0 +1,418 @@
+package validator
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// TagName is the struct tag that holds the rules of a field, separated by
+// commas, e.g.
+//
+//	Title  string   `json:"title" validate:"required,maxlen=500"`
+//	Genres []string `json:"genres" validate:"required,minlen=1,maxlen=5,unique"`
+//
+// the built-in rules are:
+//
+//	required      the field must not be the zero value (or a nil slice or pointer)
+//	optional      the field may be the zero value, which skips the other rules
+//	minlen=N      strings must be at least N bytes long, slices and maps have at least N elements
+//	maxlen=N      strings must be at most N bytes long, slices and maps have at most N elements
+//	min=N         numbers must be at least N
+//	max=N         numbers must be at most N
+//	range=N:M     numbers must be between N and M
+//	oneof=a b c   the value, or every element of a slice, must be one of the listed values
+//	unique        slices must not contain duplicate elements
+//	email         strings must be valid email addresses
+//	regex=RE      strings must match the regular expression RE, which takes up the rest of the tag
+//
+// the rules apply to zero values too, so that min=1 rejects 0, unless the field
+// is optional. more rules can be added with RegisterRule
+const TagName = "validate"
+
+// Failure describes a field that breaks a rule, with the same error code, message
+// and translation arguments that Check takes
+type Failure struct {
+	Code    string
+	Message string
+	Args    []any
+}
+
+// Rule checks a field against a rule, given the parameter after the "=" in
+// the tag (if any). it returns nil if the field is valid. values are never
+// pointers: nil pointers are passed as the zero value of the type they point
+// to. values of nil interfaces aren't valid
+type Rule func(value reflect.Value, param string) *Failure
+
+var (
+	rulesMu sync.RWMutex
+	rules   = map[string]Rule{
+		"minlen": minLenRule,
+		"maxlen": maxLenRule,
+		"min":    minRule,
+		"max":    maxRule,
+		"range":  rangeRule,
+		"oneof":  oneOfRule,
+		"unique": uniqueRule,
+		"email":  emailRule,
+		"regex":  regexRule,
+	}
+
+	// compiled regex rules, by pattern
+	patterns sync.Map
+)
+
+// RegisterRule adds a rule, or replaces one, under the given name
+func RegisterRule(name string, rule Rule) {
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+
+	rules[name] = rule
+}
+
+func lookupRule(name string) (Rule, bool) {
+	rulesMu.RLock()
+	defer rulesMu.RUnlock()
+
+	rule, ok := rules[name]
+	return rule, ok
+}
+
+// Struct checks the fields of a struct (or a pointer to one) against the rules
+// in their tags, adding an error for every field that breaks one (or for every
+// rule a field breaks, with CollectAll). fields are
+// keyed by their JSON names, and nested structs, including the ones in slices,
+// are checked too, with dotted keys like "cast.0.name". Struct panics on
+// unknown rules and malformed parameters, which are programming errors
+func (v *Validator) Struct(s any) {
+	v.validateStruct("", reflect.ValueOf(s))
+}
+
+func (v *Validator) validateStruct(prefix string, rv reflect.Value) {
+	rv = indirect(rv)
+	if rv.Kind() != reflect.Struct {
+		return
+	}
+
+	rt := rv.Type()
+
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		value := rv.Field(i)
+		tag := field.Tag.Get(TagName)
+
+		// embedded structs without a JSON name are flattened, like encoding/json does
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if field.Anonymous && name == "" && tag == "" {
+			v.validateStruct(prefix, value)
+			continue
+		}
+
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+
+		v.validateField(key, value, tag)
+		v.validateNested(key, value)
+	}
+}
+
+// validateNested checks structs within a field
+func (v *Validator) validateNested(key string, value reflect.Value) {
+	value = indirect(value)
+
+	switch value.Kind() {
+	case reflect.Struct:
+		v.validateStruct(key, value)
+
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if elem := indirect(value.Index(i)); elem.Kind() == reflect.Struct {
+				v.validateStruct(key+"."+strconv.Itoa(i), elem)
+			}
+		}
+	}
+}
+
+func (v *Validator) validateField(key string, value reflect.Value, tag string) {
+	if tag == "" {
+		return
+	}
+
+	zero := !value.IsValid() || value.IsZero()
+	split := splitRules(tag)
+	optional := slices.Contains(split, "optional")
+
+	for _, r := range split {
+		name, param, _ := strings.Cut(r, "=")
+
+		switch name {
+		case "required":
+			if zero {
+				v.AddError(key, CodeRequired, "must be provided")
+				return
+			}
+			continue
+
+		case "optional":
+			continue
+		}
+
+		rule, ok := lookupRule(name)
+		if !ok {
+			panic(fmt.Sprintf("validator: unknown rule %q on %s", name, key))
+		}
+
+		if zero && optional {
+			continue
+		}
+
+		if failure := rule(ruleValue(value), param); failure != nil {
+			v.AddError(key, failure.Code, failure.Message, failure.Args...)
+
+			if !v.CollectAll {
+				return
+			}
+		}
+	}
+}
+
+// splitRules splits a tag into its rules. a regex rule takes the rest of the tag,
+// so that its pattern may contain commas
+func splitRules(tag string) []string {
+	var split []string
+
+	for tag != "" {
+		if strings.HasPrefix(tag, "regex=") {
+			return append(split, tag)
+		}
+
+		rule, rest, _ := strings.Cut(tag, ",")
+		if rule = strings.TrimSpace(rule); rule != "" {
+			split = append(split, rule)
+		}
+		tag = strings.TrimSpace(rest)
+	}
+
+	return split
+}
+
+// indirect follows pointers down to the value they point to
+func indirect(value reflect.Value) reflect.Value {
+	for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return reflect.Value{}
+		}
+		value = value.Elem()
+	}
+
+	return value
+}
+
+// ruleValue is the value a rule checks: the value pointers point to, or the zero
+// value of the type they point to for nil pointers
+func ruleValue(value reflect.Value) reflect.Value {
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			value = reflect.Zero(value.Type().Elem())
+			continue
+		}
+		value = value.Elem()
+	}
+
+	if value.Kind() == reflect.Interface {
+		return ruleValue(indirect(value))
+	}
+
+	return value
+}
+
+// intParam parses the parameter of a rule that takes a length
+func intParam(rule, param string) int {
+	n, err := strconv.Atoi(param)
+	if err != nil {
+		panic(fmt.Sprintf("validator: %s needs an integer parameter, got %q", rule, param))
+	}
+	return n
+}
+
+// numberParam parses the parameter of a rule that takes a number
+func numberParam(rule, param string) float64 {
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		panic(fmt.Sprintf("validator: %s needs a number parameter, got %q", rule, param))
+	}
+	return n
+}
+
+// number returns the value of a numeric field
+func number(value reflect.Value) (float64, bool) {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), true
+	default:
+		return 0, false
+	}
+}
+
+func minLenRule(value reflect.Value, param string) *Failure {
+	n := intParam("minlen", param)
+
+	switch value.Kind() {
+	case reflect.String:
+		if value.Len() < n {
+			return &Failure{CodeTooShort, fmt.Sprintf("must be at least %d bytes long", n), []any{n}}
+		}
+	case reflect.Slice, reflect.Array, reflect.Map:
+		if value.Len() < n {
+			return &Failure{CodeTooFew, fmt.Sprintf("must contain at least %d elements", n), []any{n}}
+		}
+	}
+
+	return nil
+}
+
+func maxLenRule(value reflect.Value, param string) *Failure {
+	n := intParam("maxlen", param)
+
+	switch value.Kind() {
+	case reflect.String:
+		if value.Len() > n {
+			return &Failure{CodeTooLong, fmt.Sprintf("must not be more than %d bytes long", n), []any{n}}
+		}
+	case reflect.Slice, reflect.Array, reflect.Map:
+		if value.Len() > n {
+			return &Failure{CodeTooMany, fmt.Sprintf("must not contain more than %d elements", n), []any{n}}
+		}
+	}
+
+	return nil
+}
+
+func minRule(value reflect.Value, param string) *Failure {
+	minimum := numberParam("min", param)
+
+	if n, ok := number(value); ok && n < minimum {
+		return &Failure{CodeTooSmall, fmt.Sprintf("must be at least %s", param), []any{param}}
+	}
+
+	return nil
+}
+
+func maxRule(value reflect.Value, param string) *Failure {
+	maximum := numberParam("max", param)
+
+	if n, ok := number(value); ok && n > maximum {
+		return &Failure{CodeTooLarge, fmt.Sprintf("must be a maximum of %s", param), []any{param}}
+	}
+
+	return nil
+}
+
+func rangeRule(value reflect.Value, param string) *Failure {
+	from, to, ok := strings.Cut(param, ":")
+	if !ok {
+		panic(fmt.Sprintf("validator: range needs a min:max parameter, got %q", param))
+	}
+
+	minimum, maximum := numberParam("range", from), numberParam("range", to)
+
+	if n, ok := number(value); ok && (n < minimum || n > maximum) {
+		return &Failure{CodeOutOfRange, fmt.Sprintf("must be between %s and %s", from, to), []any{from, to}}
+	}
+
+	return nil
+}
+
+func oneOfRule(value reflect.Value, param string) *Failure {
+	permitted := strings.Fields(param)
+
+	values := []reflect.Value{value}
+	if value.Kind() == reflect.Slice || value.Kind() == reflect.Array {
+		values = values[:0]
+		for i := 0; i < value.Len(); i++ {
+			values = append(values, indirect(value.Index(i)))
+		}
+	}
+
+	for _, value := range values {
+		if !value.IsValid() || !PermittedValue(fmt.Sprint(value.Interface()), permitted...) {
+			message := "must be " + permitted[0]
+			if len(permitted) > 1 {
+				last := len(permitted) - 1
+				message = fmt.Sprintf("must be one of %s or %s", strings.Join(permitted[:last], ", "), permitted[last])
+			}
+
+			return &Failure{CodeNotPermitted, message, nil}
+		}
+	}
+
+	return nil
+}
+
+func uniqueRule(value reflect.Value, param string) *Failure {
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		return nil
+	}
+
+	seen := make(map[any]bool, value.Len())
+
+	for i := 0; i < value.Len(); i++ {
+		elem := value.Index(i)
+		if !elem.Comparable() {
+			continue
+		}
+
+		if seen[elem.Interface()] {
+			return &Failure{CodeDuplicate, "must not contain duplicate values", nil}
+		}
+		seen[elem.Interface()] = true
+	}
+
+	return nil
+}
+
+func emailRule(value reflect.Value, param string) *Failure {
+	if value.Kind() == reflect.String && !Matches(value.String(), EmailRX) {
+		return &Failure{CodeInvalid, "must be a valid email address", nil}
+	}
+
+	return nil
+}
+
+func regexRule(value reflect.Value, param string) *Failure {
+	rx, ok := patterns.Load(param)
+	if !ok {
+		compiled, err := regexp.Compile(param)
+		if err != nil {
+			panic(fmt.Sprintf("validator: invalid regex %q: %s", param, err))
+		}
+		rx, _ = patterns.LoadOrStore(param, compiled)
+	}
+
+	if value.Kind() == reflect.String && !Matches(value.String(), rx.(*regexp.Regexp)) {
+		return &Failure{CodeInvalid, "is not in a valid format", nil}
+	}
+
+	return nil
+}
@@ -0,0 +1,182 @@
+package validator
+
+import (
+	"reflect"
+	"testing"
+)
+
+type testPerson struct {
+	Name string `json:"name" validate:"required"`
+	Age  int    `json:"age" validate:"optional,range=1:150"`
+}
+
+type testMovie struct {
+	Title  string       `json:"title" validate:"required,maxlen=10"`
+	Year   int          `json:"year" validate:"min=1888"`
+	Rating *float64     `json:"rating" validate:"range=1:10"`
+	Code   string       `json:"code" validate:"optional,regex=^[a-z]{2,3}$"`
+	Genres []string     `json:"genres" validate:"minlen=1,unique"`
+	Cast   []testPerson `json:"cast"`
+	Lead   *testPerson  `json:"lead"`
+	Hidden string       `json:"-" validate:"required"`
+}
+
+func validMovie() testMovie {
+	rating := 7.5
+
+	return testMovie{
+		Title:  "Casablanca",
+		Year:   1942,
+		Rating: &rating,
+		Genres: []string{"drama"},
+	}
+}
+
+func TestStruct(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(m *testMovie)
+		codes  map[string]string
+	}{
+		{
+			name:   "valid",
+			modify: func(m *testMovie) {},
+			codes:  map[string]string{},
+		},
+		{
+			name:   "required",
+			modify: func(m *testMovie) { m.Title = "" },
+			codes:  map[string]string{"title": CodeRequired},
+		},
+		{
+			name:   "zero value breaks min",
+			modify: func(m *testMovie) { m.Year = 0 },
+			codes:  map[string]string{"year": CodeTooSmall},
+		},
+		{
+			name:   "nil pointer breaks range",
+			modify: func(m *testMovie) { m.Rating = nil },
+			codes:  map[string]string{"rating": CodeOutOfRange},
+		},
+		{
+			name:   "nil slice breaks minlen",
+			modify: func(m *testMovie) { m.Genres = nil },
+			codes:  map[string]string{"genres": CodeTooFew},
+		},
+		{
+			name:   "duplicates",
+			modify: func(m *testMovie) { m.Genres = []string{"drama", "drama"} },
+			codes:  map[string]string{"genres": CodeDuplicate},
+		},
+		{
+			name:   "regex with a comma",
+			modify: func(m *testMovie) { m.Code = "abc" },
+			codes:  map[string]string{},
+		},
+		{
+			name:   "regex mismatch",
+			modify: func(m *testMovie) { m.Code = "abcd" },
+			codes:  map[string]string{"code": CodeInvalid},
+		},
+		{
+			name: "nested slice",
+			modify: func(m *testMovie) {
+				m.Cast = []testPerson{{Name: "Ingrid Bergman"}, {Age: 200}}
+			},
+			codes: map[string]string{"cast.1.name": CodeRequired, "cast.1.age": CodeOutOfRange},
+		},
+		{
+			name:   "nested pointer",
+			modify: func(m *testMovie) { m.Lead = &testPerson{Name: "Humphrey Bogart", Age: -1} },
+			codes:  map[string]string{"lead.age": CodeOutOfRange},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			movie := validMovie()
+			tt.modify(&movie)
+
+			v := New()
+			v.Struct(&movie)
+
+			if !reflect.DeepEqual(v.Codes, tt.codes) {
+				t.Errorf("got codes %v; want %v", v.Codes, tt.codes)
+			}
+		})
+	}
+}
+
+func TestStructCollectAll(t *testing.T) {
+	var input struct {
+		Code string `json:"code" validate:"minlen=5,regex=^[0-9]+$"`
+	}
+	input.Code = "ab"
+
+	v := New()
+	v.Struct(input)
+
+	if got := len(v.Grouped()[0].Errors); got != 1 {
+		t.Errorf("got %d errors without CollectAll; want 1", got)
+	}
+
+	v = New()
+	v.CollectAll = true
+	v.Struct(input)
+
+	grouped := v.Grouped()
+	if len(grouped) != 1 || len(grouped[0].Errors) != 2 {
+		t.Fatalf("got %v with CollectAll; want 2 errors for code", grouped)
+	}
+	if grouped[0].Errors[0].Code != CodeTooShort || grouped[0].Errors[1].Code != CodeInvalid {
+		t.Errorf("got codes %s and %s; want %s and %s", grouped[0].Errors[0].Code, grouped[0].Errors[1].Code, CodeTooShort, CodeInvalid)
+	}
+	if v.Codes["code"] != CodeTooShort {
+		t.Errorf("got first code %s; want %s", v.Codes["code"], CodeTooShort)
+	}
+}
+
+func TestRegisterRule(t *testing.T) {
+	RegisterRule("even", func(value reflect.Value, param string) *Failure {
+		if value.Int()%2 != 0 {
+			return &Failure{Code: CodeInvalid, Message: "must be even"}
+		}
+		return nil
+	})
+
+	var input struct {
+		N int `json:"n" validate:"even"`
+	}
+
+	input.N = 3
+
+	v := New()
+	v.Struct(&input)
+
+	if v.Codes["n"] != CodeInvalid || v.Errors["n"] != "must be even" {
+		t.Errorf("got %q (%s) for 3; want the even rule to fail", v.Errors["n"], v.Codes["n"])
+	}
+
+	input.N = 4
+
+	v = New()
+	v.Struct(&input)
+
+	if !v.Valid() {
+		t.Errorf("got errors %v for 4; want none", v.Errors)
+	}
+}
+
+func TestStructUnknownRule(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("want a panic for an unknown rule")
+		}
+	}()
+
+	var input struct {
+		N int `json:"n" validate:"optional,nonsense"`
+	}
+
+	New().Struct(input)
+}
@@ -1,8 +1,10 @@
 package validator
 
 import (
+	"fmt"
 	"regexp"
 	"slices"
+	"strings"
 )
 
 // whatwg email regex
@@ -11,14 +13,62 @@ var (
 	EmailRX = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
 )
 
+// stable, machine-readable codes for validation errors. the messages that go
+// with them are meant for humans and may change, so clients should branch on
+// the codes instead. translations of the messages get the arguments listed
+// here, in this order
+const (
+	CodeRequired      = "field.required"       // missing or empty
+	CodeInvalid       = "field.invalid"        // malformed, like an email address without an @
+	CodeInvalidToken  = "field.invalid_token"  // unknown or expired token
+	CodeNotPermitted  = "field.not_permitted"  // not one of the permitted values
+	CodeTooShort      = "field.too_short"      // shorter than the minimum length; args: minimum bytes
+	CodeTooLong       = "field.too_long"       // longer than the maximum length; args: maximum bytes
+	CodeTooSmall      = "field.too_small"      // below the minimum value; args: minimum
+	CodeTooLarge      = "field.too_large"      // above the maximum value; args: maximum
+	CodeOutOfRange    = "field.out_of_range"   // outside of a range of values; args: minimum, maximum
+	CodeTooFew        = "field.too_few"        // fewer list elements than the minimum; args: minimum
+	CodeTooMany       = "field.too_many"       // more list elements than the maximum; args: maximum
+	CodeDuplicate     = "field.duplicate"      // list with repeated elements
+	CodeConflict      = "field.conflict"       // contradicts another field or the current state
+	CodeAlreadyExists = "field.already_exists" // would duplicate an existing record
+	CodeNotFound      = "field.not_found"      // refers to a record that doesn't exist
+	CodeInUse         = "field.in_use"         // refers to a record that is still in use
+)
+
+// FieldError is one of the errors of a field
+type FieldError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Args    []any  `json:"-"` // arguments for translations of the message
+}
+
+// FieldErrors are the errors of a field, in the order they were added
+type FieldErrors struct {
+	Field  string       `json:"field"`
+	Errors []FieldError `json:"errors"`
+}
+
 // validator type
 type Validator struct {
-	Errors map[string]string
+	Errors map[string]string // error messages by key
+	Codes  map[string]string // error codes by key
+	Args   map[string][]any  // message arguments by key, for translations
+
+	// CollectAll keeps every error of a key, rather than just the first, for
+	// Grouped. Errors, Codes and Args still hold the first one
+	CollectAll bool
+
+	grouped []FieldErrors
 }
 
-// New is a helper which creates a new Validator instance with an empty errors map
+// New is a helper which creates a new Validator instance with empty maps
 func New() *Validator {
-	return &Validator{Errors: make(map[string]string)}
+	return &Validator{
+		Errors: make(map[string]string),
+		Codes:  make(map[string]string),
+		Args:   make(map[string][]any),
+	}
 }
 
 // Valid returns true if no entries in error map
@@ -26,20 +76,69 @@ func (v *Validator) Valid() bool {
 	return len(v.Errors) == 0
 }
 
-// AddError adds an error message to the map (so long as the message is not empty)
-func (v *Validator) AddError(key, message string) {
+// AddError adds an error code and message to the maps (so long as there's no error for the key yet,
+// unless collecting all errors). args are the arguments for translations of the message, as listed
+// with the codes
+func (v *Validator) AddError(key, code, message string, args ...any) {
 	if _, exists := v.Errors[key]; !exists {
 		v.Errors[key] = message
+		v.Codes[key] = code
+
+		if len(args) > 0 {
+			v.Args[key] = args
+		}
+	} else if !v.CollectAll || v.Codes[key] == CodeRequired {
+		// a missing value fails every other check too, which is just noise
+		return
+	}
+
+	fieldError := FieldError{Code: code, Message: message, Args: args}
+
+	for i := range v.grouped {
+		if v.grouped[i].Field != key {
+			continue
+		}
+
+		// checks in loops may fail the same way several times
+		for _, e := range v.grouped[i].Errors {
+			if e.Code == code && e.Message == message {
+				return
+			}
+		}
+
+		v.grouped[i].Errors = append(v.grouped[i].Errors, fieldError)
+		return
 	}
+
+	v.grouped = append(v.grouped, FieldErrors{Field: key, Errors: []FieldError{fieldError}})
 }
 
-// Check adds an error message to the map only if a validation check is not ok
-func (v *Validator) Check(ok bool, key, message string) {
+// Grouped returns the errors grouped by key, in the order the keys first failed.
+// without CollectAll, every key has a single error, the same as in Errors
+func (v *Validator) Grouped() []FieldErrors {
+	grouped := make([]FieldErrors, len(v.grouped))
+	copy(grouped, v.grouped)
+	return grouped
+}
+
+// Check adds an error code and message to the maps only if a validation check is not ok
+func (v *Validator) Check(ok bool, key, code, message string, args ...any) {
 	if !ok {
-		v.AddError(key, message)
+		v.AddError(key, code, message, args...)
 	}
 }
 
+// Format formats a translated message like fmt.Sprintf, except that messages
+// without verbs are used as they are. that way, translations can leave out
+// arguments that other languages need
+func Format(message string, args ...any) string {
+	if len(args) == 0 || !strings.Contains(message, "%") {
+		return message
+	}
+
+	return fmt.Sprintf(message, args...)
+}
+
 // PermittedValue checks if a value is in a list of permitted values
 func PermittedValue[T comparable](value T, permittedValues ...T) bool {
 	return slices.Contains(permittedValues, value)
@@ -0,0 +1,79 @@
+CREATE TABLE IF NOT EXISTS genres (
+  id bigserial PRIMARY KEY,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT now(),
+  slug text UNIQUE NOT NULL,
+  name text NOT NULL,
+  version integer NOT NULL DEFAULT 1
+);
+
+CREATE TABLE IF NOT EXISTS genre_aliases (
+  alias citext PRIMARY KEY,
+  genre_id bigint NOT NULL REFERENCES genres ON DELETE CASCADE
+);
+
+-- every distinct free-text genre becomes a canonical slug. letters and digits
+-- of any script are kept, like data.Slugify does, so that "Comédie" and
+-- "Com die" don't end up with the same slug
+CREATE TEMPORARY TABLE genre_slugs AS
+SELECT DISTINCT value, trim(both '-' from regexp_replace(lower(value), '[^[:alnum:]]+', '-', 'g')) AS slug
+FROM movies
+CROSS JOIN unnest(movies.genres) AS value;
+
+INSERT INTO genres (slug, name)
+SELECT DISTINCT ON (slug) slug, initcap(value)
+FROM genre_slugs
+WHERE slug <> ''
+ORDER BY slug, value
+ON CONFLICT (slug) DO NOTHING;
+
+-- movies whose genres are all punctuation would be left without any, which
+-- genres_length_check forbids, so they get a genre of their own
+INSERT INTO genres (slug, name)
+SELECT 'uncategorized', 'Uncategorized'
+WHERE EXISTS (
+  SELECT 1
+  FROM movies
+  WHERE cardinality(movies.genres) > 0 AND NOT EXISTS (
+    SELECT 1
+    FROM unnest(movies.genres) AS genre(value)
+    INNER JOIN genre_slugs ON genre_slugs.value = genre.value
+    WHERE genre_slugs.slug <> ''
+  )
+)
+ON CONFLICT (slug) DO NOTHING;
+
+-- keep the original spellings around as aliases
+INSERT INTO genre_aliases (alias, genre_id)
+SELECT DISTINCT genre_slugs.value, genres.id
+FROM genre_slugs
+INNER JOIN genres ON genres.slug = genre_slugs.slug
+ON CONFLICT (alias) DO NOTHING;
+
+-- rewrite the movies to reference the canonical slugs, in their original
+-- order. movies that already do keep their version, so that the entity tags
+-- clients hold stay valid
+WITH canonical AS (
+  SELECT movies.id, COALESCE(
+    NULLIF(ARRAY(
+      SELECT genres.slug
+      FROM unnest(movies.genres) WITH ORDINALITY AS genre(value, position)
+      INNER JOIN genre_aliases ON genre_aliases.alias = genre.value
+      INNER JOIN genres ON genres.id = genre_aliases.genre_id
+      GROUP BY genres.slug
+      ORDER BY min(genre.position)
+    ), '{}'),
+    ARRAY['uncategorized']
+  ) AS genres
+  FROM movies
+  WHERE cardinality(movies.genres) > 0
+)
+UPDATE movies
+SET genres = canonical.genres, version = movies.version + 1
+FROM canonical
+WHERE movies.id = canonical.id AND movies.genres IS DISTINCT FROM canonical.genres;
+
+DROP TABLE genre_slugs;
+
+INSERT INTO permissions (code)
+VALUES
+  ('genres:write');
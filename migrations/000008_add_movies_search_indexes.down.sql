@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS movies_title_trgm_idx;
+DROP INDEX IF EXISTS movies_title_swedish_idx;
+DROP INDEX IF EXISTS movies_title_finnish_idx;
+DROP INDEX IF EXISTS movies_title_dutch_idx;
+DROP INDEX IF EXISTS movies_title_italian_idx;
+DROP INDEX IF EXISTS movies_title_spanish_idx;
+DROP INDEX IF EXISTS movies_title_french_idx;
+DROP INDEX IF EXISTS movies_title_german_idx;
+DROP INDEX IF EXISTS movies_title_english_idx;
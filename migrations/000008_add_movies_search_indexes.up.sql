@@ -0,0 +1,15 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- one index per text search configuration in data.SearchLanguageSafeList, so
+-- that searches in any of them can use an index; simple already has
+-- movies_title_idx from migration 000003
+CREATE INDEX IF NOT EXISTS movies_title_english_idx ON movies USING GIN (to_tsvector('english', title));
+CREATE INDEX IF NOT EXISTS movies_title_german_idx ON movies USING GIN (to_tsvector('german', title));
+CREATE INDEX IF NOT EXISTS movies_title_french_idx ON movies USING GIN (to_tsvector('french', title));
+CREATE INDEX IF NOT EXISTS movies_title_spanish_idx ON movies USING GIN (to_tsvector('spanish', title));
+CREATE INDEX IF NOT EXISTS movies_title_italian_idx ON movies USING GIN (to_tsvector('italian', title));
+CREATE INDEX IF NOT EXISTS movies_title_dutch_idx ON movies USING GIN (to_tsvector('dutch', title));
+CREATE INDEX IF NOT EXISTS movies_title_finnish_idx ON movies USING GIN (to_tsvector('finnish', title));
+CREATE INDEX IF NOT EXISTS movies_title_swedish_idx ON movies USING GIN (to_tsvector('swedish', title));
+
+CREATE INDEX IF NOT EXISTS movies_title_trgm_idx ON movies USING GIN (title gin_trgm_ops);
@@ -0,0 +1,4 @@
+DELETE FROM permissions WHERE code = 'movies:moderate';
+DROP INDEX IF EXISTS movies_deleted_at_idx;
+DELETE FROM movies WHERE deleted_at IS NOT NULL;
+ALTER TABLE movies DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS movies_revision_update ON movies;
+DROP TRIGGER IF EXISTS movies_revision_insert ON movies;
+DROP FUNCTION IF EXISTS record_movie_revision();
+DROP TABLE IF EXISTS movie_revisions;
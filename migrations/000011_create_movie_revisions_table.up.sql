@@ -0,0 +1,47 @@
+CREATE TABLE IF NOT EXISTS movie_revisions (
+  movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+  version integer NOT NULL,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT now(),
+  user_id bigint REFERENCES users ON DELETE SET NULL,
+  title text NOT NULL,
+  year integer NOT NULL,
+  runtime integer NOT NULL,
+  genres text[] NOT NULL,
+  deleted bool NOT NULL DEFAULT false,
+  PRIMARY KEY (movie_id, version)
+);
+
+-- every new version of a movie is recorded, whichever code path created it.
+-- the editor is read from the greenlight.user_id setting of the transaction
+CREATE OR REPLACE FUNCTION record_movie_revision() RETURNS trigger AS $$
+BEGIN
+  INSERT INTO movie_revisions (movie_id, version, user_id, title, year, runtime, genres, deleted)
+  VALUES (
+    NEW.id,
+    NEW.version,
+    nullif(current_setting('greenlight.user_id', true), '')::bigint,
+    NEW.title,
+    NEW.year,
+    NEW.runtime,
+    NEW.genres,
+    NEW.deleted_at IS NOT NULL
+  )
+  ON CONFLICT (movie_id, version) DO NOTHING;
+
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER movies_revision_insert
+AFTER INSERT ON movies
+FOR EACH ROW EXECUTE FUNCTION record_movie_revision();
+
+CREATE TRIGGER movies_revision_update
+AFTER UPDATE ON movies
+FOR EACH ROW WHEN (OLD.version IS DISTINCT FROM NEW.version)
+EXECUTE FUNCTION record_movie_revision();
+
+-- the current state of existing movies is their first known revision
+INSERT INTO movie_revisions (movie_id, version, created_at, title, year, runtime, genres, deleted)
+SELECT id, version, created_at, title, year, runtime, genres, deleted_at IS NOT NULL
+FROM movies;
@@ -0,0 +1,10 @@
+// Package migrations holds the SQL migrations of the database, embedded into
+// the binaries that run them
+package migrations
+
+import "embed"
+
+// FS holds the migrations, as NNNNNN_name.up.sql and NNNNNN_name.down.sql pairs
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,383 @@
+// Package client is a Go client for the greenlight API.
+//
+//	c, err := client.New("https://greenlight.example.com", client.WithCredentials(email, password))
+//	if err != nil {
+//		return err
+//	}
+//
+//	movie, err := c.Movies.Get(ctx, 1)
+//	if errors.Is(err, client.ErrNotFound) {
+//		...
+//	}
+//
+// with credentials, the client creates an authentication token on the first
+// request that needs one, and a new one when it expires. requests that are
+// rate limited (429) are retried with exponential back-off, and so are
+// idempotent requests that hit an unavailable server (503)
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// default settings, see the options to change them
+const (
+	defaultTimeout    = 30 * time.Second
+	defaultMaxRetries = 3
+	defaultMinBackoff = 500 * time.Millisecond
+	defaultMaxBackoff = 30 * time.Second
+
+	// tokens are renewed this long before they expire, so that they don't
+	// expire in flight
+	tokenExpiryMargin = time.Minute
+)
+
+// Client is a greenlight API client. it's safe for concurrent use
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	userAgent  string
+	language   string
+
+	maxRetries int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	// credentials to create authentication tokens with, if any
+	email    string
+	password string
+
+	mu    sync.Mutex
+	token *Token
+
+	Movies *MovieService
+	Users  *UserService
+	Tokens *TokenService
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client to send requests with, instead of one with
+// a 30 second timeout
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithCredentials makes the client authenticate with an email address and
+// password, creating new authentication tokens as needed
+func WithCredentials(email, password string) Option {
+	return func(c *Client) {
+		c.email = email
+		c.password = password
+	}
+}
+
+// WithToken makes the client authenticate with an existing authentication token.
+// with credentials too, a new token is created when it expires
+func WithToken(token string, expiry time.Time) Option {
+	return func(c *Client) {
+		c.token = &Token{Token: token, Expiry: expiry}
+	}
+}
+
+// WithRetries sets how many times a rate limited request, or an idempotent one
+// to an unavailable server, is retried, and the bounds of the back-off between
+// attempts. the back-off doubles with every attempt, unless the server says
+// when to retry with a Retry-After header
+func WithRetries(maxRetries int, minBackoff, maxBackoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.minBackoff = minBackoff
+		c.maxBackoff = maxBackoff
+	}
+}
+
+// WithUserAgent sets the User-Agent header of requests
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithLanguage sets the Accept-Language header of requests, for localized
+// error messages
+func WithLanguage(language string) Option {
+	return func(c *Client) {
+		c.language = language
+	}
+}
+
+// New returns a client for the API at baseURL, like https://greenlight.example.com
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("client: invalid base URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("client: invalid base URL %q: must be http or https", baseURL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+
+	c := &Client{
+		baseURL:    u,
+		httpClient: &http.Client{Timeout: defaultTimeout},
+		userAgent:  "greenlight-go-client",
+		maxRetries: defaultMaxRetries,
+		minBackoff: defaultMinBackoff,
+		maxBackoff: defaultMaxBackoff,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	c.Movies = &MovieService{client: c}
+	c.Users = &UserService{client: c}
+	c.Tokens = &TokenService{client: c}
+
+	return c, nil
+}
+
+// request is an API call. body is encoded as JSON, and the data of the response
+// decoded into out (if not nil)
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	header http.Header
+	body   any
+	out    any
+	auth   bool // whether the call needs an authentication token
+}
+
+// do sends a request, retrying it when it's rate limited or the server is
+// unavailable (see retryable), and renewing the authentication token once if
+// it's rejected
+func (c *Client) do(ctx context.Context, req request) error {
+	var body []byte
+	if req.body != nil {
+		var err error
+		body, err = json.Marshal(req.body)
+		if err != nil {
+			return fmt.Errorf("client: %w", err)
+		}
+	}
+
+	renewed := false
+
+	for attempt := 0; ; attempt++ {
+		token := ""
+		if req.auth {
+			var err error
+			token, err = c.authToken(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		res, err := c.send(ctx, req, body, token)
+		if err != nil {
+			return err
+		}
+
+		if res.StatusCode < 300 || res.StatusCode == http.StatusNotModified {
+			return decode(res, req.out)
+		}
+
+		apiErr := decodeError(res)
+
+		// an expired or revoked token is replaced, if we have the credentials to
+		if req.auth && !renewed && apiErr.Code == codeInvalidAuthenticationToken && c.email != "" {
+			renewed = true
+			c.dropToken(token)
+			continue
+		}
+
+		if retryable(req.method, res.StatusCode) && attempt < c.maxRetries {
+			err = sleep(ctx, c.backoff(attempt, res.Header.Get("Retry-After")))
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		return apiErr
+	}
+}
+
+// retryable reports whether a request that failed with the given status may be
+// sent again. rate limited requests are refused before the API handles them,
+// so they are always safe to retry. an unavailable server may have handled
+// the request anyway, so only requests that are safe to repeat are retried,
+// and never ones like POST that would create a movie or user twice
+func retryable(method string, status int) bool {
+	switch status {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusServiceUnavailable:
+		switch method {
+		case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+			return true
+		}
+	}
+	return false
+}
+
+// send makes a single attempt at a request
+func (c *Client) send(ctx context.Context, req request, body []byte, token string) (*http.Response, error) {
+	u := *c.baseURL
+	u.Path += req.path
+	u.RawQuery = req.query.Encode()
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, u.String(), reader)
+	if err != nil {
+		return nil, fmt.Errorf("client: %w", err)
+	}
+
+	for key, values := range req.header {
+		httpReq.Header[key] = values
+	}
+
+	httpReq.Header.Set("Accept", "application/json")
+	httpReq.Header.Set("User-Agent", c.userAgent)
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	if c.language != "" {
+		httpReq.Header.Set("Accept-Language", c.language)
+	}
+	if token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	res, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("client: %s %s: %w", req.method, req.path, err)
+	}
+
+	return res, nil
+}
+
+// decode reads the JSON data of a successful response into out
+func decode(res *http.Response, out any) error {
+	defer res.Body.Close()
+
+	if out == nil || res.StatusCode == http.StatusNotModified {
+		io.Copy(io.Discard, res.Body)
+		return nil
+	}
+
+	err := json.NewDecoder(res.Body).Decode(out)
+	if err != nil {
+		return fmt.Errorf("client: decoding response: %w", err)
+	}
+
+	return nil
+}
+
+// backoff returns how long to wait before retrying, after the given attempt
+func (c *Client) backoff(attempt int, retryAfter string) time.Duration {
+	if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds >= 0 {
+		return min(time.Duration(seconds)*time.Second, c.maxBackoff)
+	}
+	if at, err := http.ParseTime(retryAfter); err == nil {
+		return min(max(time.Until(at), 0), c.maxBackoff)
+	}
+
+	d := c.minBackoff << attempt
+	if d <= 0 || d > c.maxBackoff {
+		d = c.maxBackoff
+	}
+
+	// full jitter, so that clients rate limited together don't retry together
+	return d/2 + rand.N(d/2+1)
+}
+
+// sleep waits for d, or until the context is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// authToken returns a valid authentication token, creating one with the
+// credentials if there's none or it's about to expire
+func (c *Client) authToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != nil && (c.token.Expiry.IsZero() || time.Until(c.token.Expiry) > tokenExpiryMargin) {
+		return c.token.Token, nil
+	}
+
+	if c.email == "" {
+		if c.token != nil {
+			// let the server decide whether it's still good
+			return c.token.Token, nil
+		}
+		return "", ErrNoCredentials
+	}
+
+	token, err := c.Tokens.CreateAuthentication(ctx, c.email, c.password)
+	if err != nil {
+		return "", err
+	}
+
+	c.token = token
+	return token.Token, nil
+}
+
+// dropToken forgets a token the server rejected, unless another request
+// already replaced it
+func (c *Client) dropToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != nil && c.token.Token == token {
+		c.token = nil
+	}
+}
+
+// Token returns the current authentication token, if any, to store and reuse
+// with WithToken
+func (c *Client) Token() *Token {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token == nil {
+		return nil
+	}
+
+	token := *c.token
+	return &token
+}
+
+// ErrNoCredentials is returned by calls that need authentication from a
+// client without credentials or a token
+var ErrNoCredentials = errors.New("client: no credentials or authentication token")
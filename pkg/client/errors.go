@@ -0,0 +1,139 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// the codes of error responses, see the errors below
+const (
+	codeBadRequest                 = "bad_request"
+	codeFailedValidation           = "failed_validation"
+	codeNotFound                   = "not_found"
+	codeMethodNotAllowed           = "method_not_allowed"
+	codeNotAcceptable              = "not_acceptable"
+	codeEditConflict               = "edit_conflict"
+	codePreconditionFailed         = "precondition_failed"
+	codeRateLimitExceeded          = "rate_limit_exceeded"
+	codeInvalidCredentials         = "invalid_credentials"
+	codeInvalidAuthenticationToken = "invalid_authentication_token"
+	codeAuthenticationRequired     = "authentication_required"
+	codeInactiveAccount            = "inactive_account"
+	codeNotPermitted               = "not_permitted"
+	codeServerError                = "server_error"
+)
+
+// errors to match API errors against with errors.Is, by their code
+var (
+	ErrBadRequest                 = &Error{Code: codeBadRequest}
+	ErrFailedValidation           = &Error{Code: codeFailedValidation}
+	ErrNotFound                   = &Error{Code: codeNotFound}
+	ErrMethodNotAllowed           = &Error{Code: codeMethodNotAllowed}
+	ErrNotAcceptable              = &Error{Code: codeNotAcceptable}
+	ErrEditConflict               = &Error{Code: codeEditConflict}
+	ErrPreconditionFailed         = &Error{Code: codePreconditionFailed}
+	ErrRateLimitExceeded          = &Error{Code: codeRateLimitExceeded}
+	ErrInvalidCredentials         = &Error{Code: codeInvalidCredentials}
+	ErrInvalidAuthenticationToken = &Error{Code: codeInvalidAuthenticationToken}
+	ErrAuthenticationRequired     = &Error{Code: codeAuthenticationRequired}
+	ErrInactiveAccount            = &Error{Code: codeInactiveAccount}
+	ErrNotPermitted               = &Error{Code: codeNotPermitted}
+	ErrServerError                = &Error{Code: codeServerError}
+)
+
+// FieldErrors are the errors of a field of a failed validation
+type FieldErrors struct {
+	Field  string       `json:"field"`
+	Errors []FieldError `json:"errors"`
+}
+
+// FieldError is one of the errors of a field, with a code like "field.required"
+type FieldError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error is an error response of the API
+type Error struct {
+	StatusCode int    // HTTP status code
+	Code       string // stable code, like "not_found"
+	Message    string // human-readable message, localized with WithLanguage
+
+	// for failed validations: the first error of each field, their codes, and
+	// every error of every field
+	Fields     map[string]string
+	FieldCodes map[string]string
+	Details    []FieldErrors
+}
+
+func (e *Error) Error() string {
+	if len(e.Fields) == 0 {
+		return fmt.Sprintf("greenlight: %s (%d %s)", e.Message, e.StatusCode, e.Code)
+	}
+
+	fields := make([]string, 0, len(e.Fields))
+	for field, message := range e.Fields {
+		fields = append(fields, field+": "+message)
+	}
+	sort.Strings(fields)
+
+	return fmt.Sprintf("greenlight: %s (%d %s): %s", e.Message, e.StatusCode, e.Code, strings.Join(fields, "; "))
+}
+
+// Is matches errors with the same code, so that errors.Is(err, ErrNotFound)
+// works for any not found response
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// decodeError reads an error response, either an error envelope or RFC 9457
+// problem details
+func decodeError(res *http.Response) *Error {
+	defer res.Body.Close()
+
+	apiErr := &Error{
+		StatusCode: res.StatusCode,
+		Message:    strings.ToLower(http.StatusText(res.StatusCode)),
+	}
+
+	var body struct {
+		Code       string            `json:"code"`
+		Error      json.RawMessage   `json:"error"`
+		Detail     string            `json:"detail"`
+		Errors     map[string]string `json:"errors"`
+		FieldCodes map[string]string `json:"field_codes"`
+		Details    []FieldErrors     `json:"details"`
+	}
+
+	data, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil || json.Unmarshal(data, &body) != nil {
+		// not from the API, like a proxy error page
+		return apiErr
+	}
+
+	apiErr.Code = body.Code
+	apiErr.FieldCodes = body.FieldCodes
+	apiErr.Details = body.Details
+
+	if body.Detail != "" {
+		apiErr.Message = body.Detail
+	}
+	if body.Errors != nil {
+		apiErr.Fields = body.Errors
+	}
+
+	// the error of an envelope is a message, or the errors of the fields
+	var message string
+	if json.Unmarshal(body.Error, &message) == nil {
+		apiErr.Message = message
+	} else if json.Unmarshal(body.Error, &apiErr.Fields) == nil && body.Detail == "" {
+		apiErr.Message = "the request contains invalid fields"
+	}
+
+	return apiErr
+}
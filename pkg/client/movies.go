@@ -0,0 +1,347 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Movie is a movie of the catalogue. fields left out with MovieFilter.Fields
+// are zero
+type Movie struct {
+	ID      int64    `json:"id"`
+	Title   string   `json:"title"`
+	Year    int32    `json:"year,omitempty"`
+	Runtime Runtime  `json:"runtime,omitempty"`
+	Genres  []string `json:"genres,omitempty"`
+	Version int32    `json:"version"`
+}
+
+// Runtime is the runtime of a movie in minutes, which the API writes as
+// "<minutes> mins"
+type Runtime int32
+
+func (r Runtime) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(fmt.Sprintf("%d mins", r))), nil
+}
+
+func (r *Runtime) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return fmt.Errorf("client: invalid runtime %s", b)
+	}
+
+	minutes, ok := strings.CutSuffix(s, " mins")
+	if !ok {
+		return fmt.Errorf("client: invalid runtime %q", s)
+	}
+
+	n, err := strconv.ParseInt(minutes, 10, 32)
+	if err != nil {
+		return fmt.Errorf("client: invalid runtime %q", s)
+	}
+
+	*r = Runtime(n)
+	return nil
+}
+
+// Metadata describes a page of results. pages are numbered unless they were
+// requested with a cursor
+type Metadata struct {
+	CurrentPage  int    `json:"current_page,omitempty"`
+	PageSize     int    `json:"page_size,omitempty"`
+	FirstPage    int    `json:"first_page,omitempty"`
+	LastPage     int    `json:"last_page,omitempty"`
+	TotalRecords int    `json:"total_records,omitempty"`
+	NextCursor   string `json:"next_cursor,omitempty"`
+	PrevCursor   string `json:"prev_cursor,omitempty"`
+}
+
+// MovieService calls the movie endpoints
+type MovieService struct {
+	client *Client
+}
+
+// MovieInput is a movie to create
+type MovieInput struct {
+	Title   string   `json:"title"`
+	Year    int32    `json:"year"`
+	Runtime Runtime  `json:"runtime"`
+	Genres  []string `json:"genres"`
+}
+
+// MovieUpdate is a partial update of a movie; nil fields are left alone
+type MovieUpdate struct {
+	Title   *string  `json:"title,omitempty"`
+	Year    *int32   `json:"year,omitempty"`
+	Runtime *Runtime `json:"runtime,omitempty"`
+	Genres  []string `json:"genres,omitempty"`
+
+	// if set, the update fails with ErrPreconditionFailed unless the movie is
+	// still at this version
+	Version int32 `json:"-"`
+}
+
+// MovieFilter selects and orders the movies to list. zero fields are left to the
+// API's defaults
+type MovieFilter struct {
+	Title      string   // title search
+	SearchMode string   // plain, prefix or fuzzy
+	Language   string   // language of the title search, like english
+	Genres     []string // genres to match, or to exclude with a "-" prefix
+	GenreMode  string   // all or any
+	YearFrom   int
+	YearTo     int
+	RuntimeMin int
+	RuntimeMax int
+
+	Sort     string   // like title or -year
+	Fields   []string // fields to return; the id and version are always returned
+	Page     int
+	PageSize int
+	Cursor   string // next or previous cursor of a page, for keyset pagination
+}
+
+// query returns the filter as a query string
+func (f MovieFilter) query() url.Values {
+	qs := url.Values{}
+
+	set := func(key, value string) {
+		if value != "" {
+			qs.Set(key, value)
+		}
+	}
+	setInt := func(key string, value int) {
+		if value != 0 {
+			qs.Set(key, strconv.Itoa(value))
+		}
+	}
+
+	set("title", f.Title)
+	set("search_mode", f.SearchMode)
+	set("lang", f.Language)
+	set("genres", strings.Join(f.Genres, ","))
+	set("genre_mode", f.GenreMode)
+	setInt("year_from", f.YearFrom)
+	setInt("year_to", f.YearTo)
+	setInt("runtime_min", f.RuntimeMin)
+	setInt("runtime_max", f.RuntimeMax)
+	set("sort", f.Sort)
+	set("fields", strings.Join(f.Fields, ","))
+	setInt("page", f.Page)
+	setInt("page_size", f.PageSize)
+
+	if f.Cursor != "" {
+		qs.Set("pagination", "cursor")
+		qs.Set("cursor", f.Cursor)
+	}
+
+	return qs
+}
+
+// MoviePage is a page of movies
+type MoviePage struct {
+	Movies   []*Movie `json:"movies"`
+	Metadata Metadata `json:"metadata"`
+}
+
+// List returns a page of the movies matching filter
+func (s *MovieService) List(ctx context.Context, filter MovieFilter) (*MoviePage, error) {
+	return s.list(ctx, filter.query())
+}
+
+func (s *MovieService) list(ctx context.Context, query url.Values) (*MoviePage, error) {
+	var page MoviePage
+
+	err := s.client.do(ctx, request{
+		method: http.MethodGet,
+		path:   "/v1/movies",
+		query:  query,
+		out:    &page,
+		auth:   true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &page, nil
+}
+
+// Get returns the movie with the given id
+func (s *MovieService) Get(ctx context.Context, id int64) (*Movie, error) {
+	var out struct {
+		Movie *Movie `json:"movie"`
+	}
+
+	err := s.client.do(ctx, request{
+		method: http.MethodGet,
+		path:   fmt.Sprintf("/v1/movies/%d", id),
+		out:    &out,
+		auth:   true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return out.Movie, nil
+}
+
+// Create creates a movie
+func (s *MovieService) Create(ctx context.Context, input MovieInput) (*Movie, error) {
+	var out struct {
+		Movie *Movie `json:"movie"`
+	}
+
+	err := s.client.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/v1/movies",
+		body:   input,
+		out:    &out,
+		auth:   true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return out.Movie, nil
+}
+
+// Update updates the given fields of a movie
+func (s *MovieService) Update(ctx context.Context, id int64, update MovieUpdate) (*Movie, error) {
+	var out struct {
+		Movie *Movie `json:"movie"`
+	}
+
+	header := http.Header{}
+	if update.Version != 0 {
+		header.Set("If-Match", strconv.Quote(fmt.Sprintf("%d-%d", id, update.Version)))
+	}
+
+	err := s.client.do(ctx, request{
+		method: http.MethodPatch,
+		path:   fmt.Sprintf("/v1/movies/%d", id),
+		header: header,
+		body:   update,
+		out:    &out,
+		auth:   true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return out.Movie, nil
+}
+
+// Delete moves a movie to the trash
+func (s *MovieService) Delete(ctx context.Context, id int64) error {
+	err := s.client.do(ctx, request{
+		method: http.MethodDelete,
+		path:   fmt.Sprintf("/v1/movies/%d", id),
+		auth:   true,
+	})
+
+	return err
+}
+
+// All returns an iterator over every movie matching filter, starting at its page
+// or cursor, if any:
+//
+//	it := c.Movies.All(ctx, client.MovieFilter{Genres: []string{"drama"}})
+//	for it.Next() {
+//		movie := it.Movie()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+//
+// pages are fetched as the iteration needs them, with keyset pagination, except
+// when sorting by relevance, which only works with page numbers
+func (s *MovieService) All(ctx context.Context, filter MovieFilter) *MovieIterator {
+	query := filter.query()
+	if filter.Sort != "relevance" {
+		query.Set("pagination", "cursor")
+	}
+
+	return &MovieIterator{service: s, ctx: ctx, query: query}
+}
+
+// MovieIterator iterates over the movies of consecutive pages
+type MovieIterator struct {
+	service *MovieService
+	ctx     context.Context
+	query   url.Values // of the next page; nil after the last one
+
+	movies []*Movie
+	movie  *Movie
+	meta   Metadata
+	err    error
+}
+
+// Next advances to the next movie, fetching the next page if needed. it returns
+// false when there are no more movies, or on errors
+func (it *MovieIterator) Next() bool {
+	for len(it.movies) == 0 {
+		if it.err != nil || it.query == nil {
+			it.movie = nil
+			return false
+		}
+
+		page, err := it.service.list(it.ctx, it.query)
+		if err != nil {
+			it.err = err
+			continue
+		}
+
+		it.movies = page.Movies
+		it.meta = page.Metadata
+		it.query = nextPage(it.query, page.Metadata)
+	}
+
+	it.movie, it.movies = it.movies[0], it.movies[1:]
+	return true
+}
+
+// nextPage returns the query for the page after the one with the given metadata,
+// or nil if it's the last one
+func nextPage(query url.Values, meta Metadata) url.Values {
+	next := url.Values{}
+	for key, values := range query {
+		next[key] = values
+	}
+
+	switch {
+	case query.Get("pagination") == "cursor":
+		if meta.NextCursor == "" {
+			return nil
+		}
+		next.Set("cursor", meta.NextCursor)
+		next.Del("page")
+
+	case meta.CurrentPage != 0 && meta.CurrentPage < meta.LastPage:
+		next.Set("page", strconv.Itoa(meta.CurrentPage+1))
+
+	default:
+		return nil
+	}
+
+	return next
+}
+
+// Movie returns the current movie
+func (it *MovieIterator) Movie() *Movie {
+	return it.movie
+}
+
+// Metadata returns the metadata of the current page
+func (it *MovieIterator) Metadata() Metadata {
+	return it.meta
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *MovieIterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,44 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Token is an authentication token
+type Token struct {
+	Token  string    `json:"token"`
+	Expiry time.Time `json:"expiry"`
+}
+
+// TokenService calls the token endpoints
+type TokenService struct {
+	client *Client
+}
+
+// CreateAuthentication creates an authentication token for the user with the
+// given email address and password. the client already does this when it has
+// credentials, see WithCredentials
+func (s *TokenService) CreateAuthentication(ctx context.Context, email, password string) (*Token, error) {
+	input := struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}{email, password}
+
+	var out struct {
+		Token *Token `json:"authentication_token"`
+	}
+
+	err := s.client.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/v1/tokens/authentication",
+		body:   input,
+		out:    &out,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return out.Token, nil
+}
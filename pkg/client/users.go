@@ -0,0 +1,69 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// User is a user account
+type User struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Activated bool      `json:"activated"`
+}
+
+// UserService calls the user endpoints
+type UserService struct {
+	client *Client
+}
+
+// Register creates a user, who is sent an activation token by email
+func (s *UserService) Register(ctx context.Context, name, email, password string) (*User, error) {
+	input := struct {
+		Name     string `json:"name"`
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}{name, email, password}
+
+	var out struct {
+		User *User `json:"user"`
+	}
+
+	err := s.client.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/v1/users",
+		body:   input,
+		out:    &out,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return out.User, nil
+}
+
+// Activate activates the user an activation token was sent to
+func (s *UserService) Activate(ctx context.Context, token string) (*User, error) {
+	input := struct {
+		Token string `json:"token"`
+	}{token}
+
+	var out struct {
+		User *User `json:"user"`
+	}
+
+	err := s.client.do(ctx, request{
+		method: http.MethodPut,
+		path:   "/v1/users/activated",
+		body:   input,
+		out:    &out,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return out.User, nil
+}